@@ -0,0 +1,85 @@
+package chroma
+
+var bracketPairs = map[byte]byte{'(': ')', '[': ']', '{': '}'}
+
+type bracket struct {
+	offset int
+	char   byte
+}
+
+// MatchBracket finds the bracket matching the one at byteOffset in the source
+// text of tokens.
+//
+// Only brackets within Punctuation tokens are considered, so brackets inside
+// strings and comments are ignored. The returned ranges are the [start, end)
+// byte offsets of the opening and closing brackets. ok is false if there is no
+// bracket at byteOffset or it is unbalanced.
+func MatchBracket(tokens []Token, byteOffset int) (openRange, closeRange [2]int, ok bool) {
+	brackets := []bracket{}
+	cursor := -1
+	offset := 0
+	for _, token := range tokens {
+		if token.Type.InCategory(Punctuation) {
+			for i := 0; i < len(token.Value); i++ {
+				switch c := token.Value[i]; c {
+				case '(', ')', '[', ']', '{', '}':
+					if offset+i == byteOffset {
+						cursor = len(brackets)
+					}
+					brackets = append(brackets, bracket{offset + i, c})
+				}
+			}
+		}
+		offset += len(token.Value)
+	}
+	if cursor < 0 {
+		return openRange, closeRange, false
+	}
+	start := brackets[cursor]
+	if _, isOpen := bracketPairs[start.char]; isOpen {
+		end, ok := findBracket(brackets[cursor:], 1)
+		return [2]int{start.offset, start.offset + 1}, [2]int{end.offset, end.offset + 1}, ok
+	}
+	end, ok := findBracket(brackets[:cursor+1], -1)
+	return [2]int{end.offset, end.offset + 1}, [2]int{start.offset, start.offset + 1}, ok
+}
+
+// findBracket walks brackets from the first (dir > 0) or last (dir < 0)
+// element, returning the bracket that balances it.
+func findBracket(brackets []bracket, dir int) (bracket, bool) {
+	stack := []byte{}
+	i, end := 0, len(brackets)
+	if dir < 0 {
+		i, end = len(brackets)-1, -1
+	}
+	for ; i != end; i += dir {
+		c := brackets[i].char
+		closing, isOpen := bracketPairs[c]
+		if dir < 0 {
+			// Walking backwards, closing brackets open a nesting level.
+			isOpen = !isOpen
+			closing = reverseBracket(c)
+		}
+		if isOpen {
+			stack = append(stack, closing)
+			continue
+		}
+		if len(stack) == 0 || stack[len(stack)-1] != c {
+			return bracket{}, false
+		}
+		stack = stack[:len(stack)-1]
+		if len(stack) == 0 {
+			return brackets[i], true
+		}
+	}
+	return bracket{}, false
+}
+
+func reverseBracket(c byte) byte {
+	for open, closing := range bracketPairs {
+		if closing == c {
+			return open
+		}
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchBracket(t *testing.T) {
+	// f(a[0], "(", {b})
+	tokens := []Token{
+		{Name, "f"},
+		{Punctuation, "("},
+		{Name, "a"},
+		{Punctuation, "["},
+		{Number, "0"},
+		{Punctuation, "],"},
+		{Whitespace, " "},
+		{String, `"("`},
+		{Punctuation, ","},
+		{Whitespace, " "},
+		{Punctuation, "{"},
+		{Name, "b"},
+		{Punctuation, "})"},
+	}
+	tests := []struct {
+		name   string
+		offset int
+		open   [2]int
+		close  [2]int
+		ok     bool
+	}{
+		{"Outer", 1, [2]int{1, 2}, [2]int{16, 17}, true},
+		{"OuterReverse", 16, [2]int{1, 2}, [2]int{16, 17}, true},
+		{"Nested", 3, [2]int{3, 4}, [2]int{5, 6}, true},
+		{"NestedInMultiCharToken", 15, [2]int{13, 14}, [2]int{15, 16}, true},
+		{"InString", 9, [2]int{}, [2]int{}, false},
+		{"NotBracket", 0, [2]int{}, [2]int{}, false},
+	}
+	for _, test := range tests {
+		// nolint: scopelint
+		t.Run(test.name, func(t *testing.T) {
+			openRange, closeRange, ok := MatchBracket(tokens, test.offset)
+			assert.Equal(t, test.ok, ok)
+			if test.ok {
+				assert.Equal(t, test.open, openRange)
+				assert.Equal(t, test.close, closeRange)
+			}
+		})
+	}
+}
+
+func TestMatchBracketUnbalanced(t *testing.T) {
+	tokens := []Token{{Punctuation, "(["}, {Name, "a"}, {Punctuation, ")"}}
+	_, _, ok := MatchBracket(tokens, 0)
+	assert.False(t, ok)
+	_, _, ok = MatchBracket(tokens, 1)
+	assert.False(t, ok)
+	_, _, ok = MatchBracket(tokens, 3)
+	assert.False(t, ok)
+
+	tokens = []Token{{Punctuation, "(("}, {Punctuation, ")"}}
+	_, _, ok = MatchBracket(tokens, 0)
+	assert.False(t, ok)
+	openRange, closeRange, ok := MatchBracket(tokens, 1)
+	assert.True(t, ok)
+	assert.Equal(t, [2]int{1, 2}, openRange)
+	assert.Equal(t, [2]int{2, 3}, closeRange)
+}
@@ -0,0 +1,34 @@
+package chroma
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// CacheKey returns a stable key identifying the output of highlighting text
+// with the named lexer, style and formatter, eg. for caching rendered output.
+//
+// A nil options is equivalent to the default options used by lexers. The
+// OnEmptyMatch callback does not affect output and is ignored.
+func CacheKey(lexerName, styleName, formatterName string, options *TokeniseOptions, text string) string {
+	if options == nil {
+		options = defaultOptions
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "chroma/v2\n%q\n%q\n%q\n", lexerName, styleName, formatterName)
+	fmt.Fprintf(h, "%q %t %t %t %t %d %t %d %t\n",
+		options.State, options.Nested, options.EnsureLF, options.Dedent, options.PreserveEOF,
+		options.MaxMatchLength, options.TruncateLongMatches, options.MaxLineLexLength, options.FailOnEmptyMatch)
+	keywords := make([]string, 0, len(options.ExtraKeywords))
+	for keyword := range options.ExtraKeywords {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		fmt.Fprintf(h, "%q %d\n", keyword, options.ExtraKeywords[keyword])
+	}
+	fmt.Fprintf(h, "%d\n%s", len(text), text)
+	return hex.EncodeToString(h.Sum(nil))
+}
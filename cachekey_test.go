@@ -0,0 +1,31 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheKey(t *testing.T) {
+	key := CacheKey("go", "monokai", "html", nil, "package main\n")
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, CacheKey("go", "monokai", "html", nil, "package main\n"))
+	assert.Equal(t, key, CacheKey("go", "monokai", "html", &TokeniseOptions{State: "root", EnsureLF: true}, "package main\n"))
+
+	variants := []string{
+		CacheKey("golang", "monokai", "html", nil, "package main\n"),
+		CacheKey("go", "dracula", "html", nil, "package main\n"),
+		CacheKey("go", "monokai", "svg", nil, "package main\n"),
+		CacheKey("go", "monokai", "html", &TokeniseOptions{State: "root"}, "package main\n"),
+		CacheKey("go", "monokai", "html", &TokeniseOptions{State: "root", EnsureLF: true, MaxLineLexLength: 10}, "package main\n"),
+		CacheKey("go", "monokai", "html", &TokeniseOptions{State: "root", EnsureLF: true, ExtraKeywords: map[string]TokenType{"main": Keyword}}, "package main\n"),
+		CacheKey("go", "monokai", "html", nil, "package main"),
+		// Fields must not run together.
+		CacheKey("gomonokai", "", "html", nil, "package main\n"),
+	}
+	seen := map[string]bool{key: true}
+	for i, variant := range variants {
+		assert.False(t, seen[variant], "variant %d", i)
+		seen[variant] = true
+	}
+}
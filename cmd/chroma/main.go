@@ -57,6 +57,7 @@ command, for Go.
 		JSON      bool   `group:"format" help:"Convenience flag to use JSON formatter."`
 		HTML      bool   `group:"format" help:"Convenience flag to use HTML formatter."`
 		SVG       bool   `group:"format" help:"Convenience flag to use SVG formatter."`
+		Pager     bool   `group:"format" help:"Tune terminal output for pagers such as less -R."`
 
 		HTMLPrefix                string `group:"html" help:"HTML CSS class prefix." placeholder:"PREFIX"`
 		HTMLStyles                bool   `group:"html" help:"Output HTML CSS styles."`
@@ -345,6 +346,9 @@ func selexer(path, contents string) (lexer chroma.Lexer) {
 
 func format(ctx *kong.Context, w io.Writer, style *chroma.Style, it chroma.Iterator) {
 	formatter := formatters.Get(cli.Formatter)
+	if cli.Pager {
+		formatter = formatters.Pager(formatter)
+	}
 	err := formatter.Format(w, style, it)
 	ctx.FatalIfErrorf(err)
 }
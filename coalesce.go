@@ -4,10 +4,29 @@ import (
 	"fmt"
 )
 
+// A CoalesceOption configures Coalesce.
+type CoalesceOption func(c *coalescer)
+
+// TransparentZeroWidth preserves zero-width tokens, which are otherwise
+// dropped, without letting them prevent adjacent tokens of the same type from
+// being merged. Zero-width tokens are re-emitted after the merged token.
+func TransparentZeroWidth() CoalesceOption {
+	return func(c *coalescer) { c.transparentZeroWidth = true }
+}
+
 // Coalesce is a Lexer interceptor that collapses runs of common types into a single token.
-func Coalesce(lexer Lexer) Lexer { return &coalescer{lexer} }
+func Coalesce(lexer Lexer, options ...CoalesceOption) Lexer {
+	c := &coalescer{Lexer: lexer}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
 
-type coalescer struct{ Lexer }
+type coalescer struct {
+	Lexer
+	transparentZeroWidth bool
+}
 
 func (d *coalescer) Tokenise(options *TokeniseOptions, text string) (Iterator, error) {
 	it, err := d.Lexer.Tokenise(options, text)
@@ -18,26 +37,42 @@ func (d *coalescer) Tokenise(options *TokeniseOptions, text string) (Iterator, e
 }
 
 func (d *coalescer) iter(it func() Token) func() Token {
-	var prev Token
+	var (
+		prev    Token
+		markers []Token
+		queue   []Token
+	)
 	return func() Token {
-		for token := it(); token != (EOF); token = it() {
+		for len(queue) == 0 {
+			token := it()
+			if token == EOF {
+				if prev == EOF {
+					return EOF
+				}
+				queue = append(append(queue, prev), markers...)
+				prev, markers = EOF, nil
+				break
+			}
 			if len(token.Value) == 0 {
+				if d.transparentZeroWidth {
+					if prev == EOF {
+						return token
+					}
+					markers = append(markers, token)
+				}
 				continue
 			}
 			if prev == EOF {
 				prev = token
+			} else if prev.Type == token.Type && len(prev.Value) < 8192 {
+				prev.Value += token.Value
 			} else {
-				if prev.Type == token.Type && len(prev.Value) < 8192 {
-					prev.Value += token.Value
-				} else {
-					out := prev
-					prev = token
-					return out
-				}
+				queue = append(append(queue, prev), markers...)
+				prev, markers = token, nil
 			}
 		}
-		out := prev
-		prev = EOF
+		out := queue[0]
+		queue = queue[1:]
 		return out
 	}
 }
@@ -17,3 +17,28 @@ func TestCoalesce(t *testing.T) {
 	expected := []Token{{Punctuation, "!@#$"}}
 	assert.Equal(t, expected, actual)
 }
+
+func TestCoalesceTransparentZeroWidth(t *testing.T) {
+	lexer := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": []Rule{
+			{`\|`, EmitterFunc(func(groups []string, state *LexerState) Iterator {
+				return Literator(Token{Cursor, ""})
+			}), nil},
+			{`[!@#$%^&*()]`, Punctuation, nil},
+			{`\w`, Name, nil},
+		},
+	})
+	actual, err := Tokenise(Coalesce(lexer), nil, "|!@|#a|b")
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{{Punctuation, "!@#"}, {Name, "ab"}}, actual)
+
+	actual, err = Tokenise(Coalesce(lexer, TransparentZeroWidth()), nil, "|!@|#a|b")
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Cursor, ""},
+		{Punctuation, "!@#"},
+		{Cursor, ""},
+		{Name, "ab"},
+		{Cursor, ""},
+	}, actual)
+}
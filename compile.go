@@ -0,0 +1,51 @@
+package chroma
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+func init() { // nolint: gochecknoinits
+	for _, emitter := range emitterTemplates {
+		gob.Register(emitter)
+	}
+	for _, mutator := range mutatorTemplates {
+		gob.Register(mutator)
+	}
+}
+
+type compiledLexer struct {
+	Config *Config
+	Rules  Rules
+}
+
+// Compile a RegexLexer to a compact binary form that can be loaded with
+// LoadCompiled much faster than parsing XML.
+//
+// As with Marshal, the lexer's rules must be serialisable. Analysers are not
+// preserved.
+func Compile(lexer *RegexLexer) ([]byte, error) {
+	rules, err := lexer.Rules()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	err = gob.NewEncoder(buf).Encode(&compiledLexer{Config: lexer.Config(), Rules: rules})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v: %w", lexer.Config().Name, err, ErrNotSerialisable)
+	}
+	return buf.Bytes(), nil
+}
+
+// LoadCompiled loads a RegexLexer previously compiled with Compile.
+func LoadCompiled(data []byte) (*RegexLexer, error) {
+	compiled := &compiledLexer{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(compiled); err != nil {
+		return nil, fmt.Errorf("invalid compiled lexer: %w", err)
+	}
+	if err := validateStateCount(compiled.Rules); err != nil {
+		return nil, fmt.Errorf("invalid compiled lexer: %w", err)
+	}
+	return NewLexer(compiled.Config, func() Rules { return compiled.Rules })
+}
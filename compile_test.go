@@ -0,0 +1,54 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileRoundTrip(t *testing.T) {
+	actual := MustNewLexer(&Config{
+		Name:      "Test",
+		Aliases:   []string{"test"},
+		Filenames: []string{"*.test"},
+		EnsureNL:  true,
+	}, func() Rules {
+		return Rules{
+			"root": {
+				{`#.*$`, CommentSingle, nil},
+				{`^(\w+)(=)`, ByGroups(NameAttribute, Operator), nil},
+				{`(\w+)(\()`, ByGroups(UsingSelf("inner"), Punctuation), Mutators(Push("args"), Push("args"))},
+				{`"`, String, Combined("string", "args")},
+				Include("other"),
+			},
+			"args": {
+				{`\)`, Punctuation, Pop(1)},
+				{`\w+`, UsingByGroup(1, 2, Name), nil},
+			},
+			"string": {
+				{`"`, String, Pop(1)},
+				{`[^"]+`, Using("Go"), nil},
+			},
+			"inner": {
+				{`.`, Text, nil},
+			},
+			"other": {
+				{`.`, Text, nil},
+			},
+		}
+	})
+	data, err := Compile(actual)
+	require.NoError(t, err)
+	expected, err := LoadCompiled(data)
+	require.NoError(t, err)
+	require.Equal(t, actual.Config(), expected.Config())
+	require.Equal(t, mustRules(t, actual), mustRules(t, expected))
+
+	_, err = Compile(MustNewLexer(&Config{Name: "Func"}, func() Rules {
+		return Rules{"root": {{`.`, EmitterFunc(func(groups []string, state *LexerState) Iterator { return nil }), nil}}}
+	}))
+	require.ErrorIs(t, err, ErrNotSerialisable)
+
+	_, err = LoadCompiled([]byte("garbage"))
+	require.Error(t, err)
+}
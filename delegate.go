@@ -3,13 +3,30 @@ package chroma
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
 type delegatingLexer struct {
-	root     Lexer
-	language Lexer
+	root      Lexer
+	language  Lexer
+	alignment DelegateAlignment
 }
 
+// DelegateAlignment controls how a DelegatingLexer handles a root lexer that
+// emits tokens whose combined length differs from the "Other" text it was given.
+type DelegateAlignment int
+
+const (
+	// DelegateUnchecked interleaves root tokens without checking their length.
+	DelegateUnchecked DelegateAlignment = iota
+	// DelegateStrict returns an error if the root tokens do not exactly cover the "Other" text.
+	DelegateStrict
+	// DelegateLenient realigns root tokens by position against the "Other" text,
+	// dropping tokens that do not occur in it and filling text the root lexer
+	// skipped with Text tokens, so that the root tokens exactly reproduce it.
+	DelegateLenient
+)
+
 // DelegatingLexer combines two lexers to handle the common case of a language embedded inside another, such as PHP
 // inside HTML or PHP inside plain text.
 //
@@ -25,6 +42,16 @@ func DelegatingLexer(root Lexer, language Lexer) Lexer {
 	}
 }
 
+// DelegatingLexerWithAlignment is like DelegatingLexer but checks or corrects
+// the length of the root lexer's output according to alignment.
+func DelegatingLexerWithAlignment(root Lexer, language Lexer, alignment DelegateAlignment) Lexer {
+	return &delegatingLexer{
+		root:      root,
+		language:  language,
+		alignment: alignment,
+	}
+}
+
 func (d *delegatingLexer) AnalyseText(text string) float32 {
 	return d.root.AnalyseText(text)
 }
@@ -87,32 +114,19 @@ type tokenizeWithOriginalLen func(lexer Lexer, options *TokeniseOptions, text st
 type tokenizeRootWithOriginalLen func(options *TokeniseOptions, text string) (Iterator, OriginalLenIterator, error)
 
 func (d *delegatingLexer) tokenise(options *TokeniseOptions, tokeniseFn tokenizeWithOriginalLen, tokenizeRootFn tokenizeRootWithOriginalLen, text string) (Iterator, OriginalLenIterator, error) { // nolint: gocognit
+	// Dedent once up front, so that the root lexer isn't passed "Other" text
+	// that has already been dedented.
+	if options != nil && options.Dedent {
+		text = dedent(text)
+		dedented := *options
+		dedented.Dedent = false
+		options = &dedented
+	}
 	tokens, offsetIter, err := tokeniseFn(Coalesce(d.language), options, text)
 	if err != nil {
 		return nil, OriginalLenIterator{}, err
 	}
-	// Compute insertions and gather "Other" tokens.
-	others := &bytes.Buffer{}
-	insertions := []*insertion{}
-	var insert *insertion
-	offset := 0
-	var last Token
-	for _, t := range tokens {
-		if t.Type == Other {
-			if last != EOF && insert != nil && last.Type != Other {
-				insert.end = offset
-			}
-			others.WriteString(t.Value)
-		} else {
-			if last == EOF || last.Type == Other {
-				insert = &insertion{start: offset}
-				insertions = append(insertions, insert)
-			}
-			insert.tokens = append(insert.tokens, t)
-		}
-		last = t
-		offset += len(t.Value)
-	}
+	others, insertions := computeInsertions(tokens)
 
 	if len(insertions) == 0 {
 		// No insertions, so just return the iterator from the root lexer
@@ -120,14 +134,18 @@ func (d *delegatingLexer) tokenise(options *TokeniseOptions, tokeniseFn tokenize
 	}
 
 	// Lex the other tokens.
-	rootTokens, err := Tokenise(Coalesce(d.root), options, others.String())
+	rootTokens, err := Tokenise(Coalesce(d.root), options, others)
+	if err != nil {
+		return nil, OriginalLenIterator{}, err
+	}
+	rootTokens, err = d.align(rootTokens, others)
 	if err != nil {
 		return nil, OriginalLenIterator{}, err
 	}
 
 	// Interleave the two sets of tokens.
 	var out []Token
-	offset = 0 // Offset into text.
+	offset := 0 // Offset into text.
 	tokenIndex := 0
 	nextToken := func() Token {
 		if tokenIndex >= len(rootTokens) {
@@ -172,6 +190,83 @@ func (d *delegatingLexer) tokenise(options *TokeniseOptions, tokeniseFn tokenize
 	return Literator(out...), offsetIter, nil
 }
 
+// computeInsertions returns the insertions of non-"Other" tokens, and the
+// concatenated "Other" text for the root lexer.
+func computeInsertions(tokens []Token) (string, []*insertion) {
+	others := &bytes.Buffer{}
+	insertions := []*insertion{}
+	var insert *insertion
+	offset := 0
+	var last Token
+	for _, t := range tokens {
+		if t.Type == Other {
+			if last != EOF && insert != nil && last.Type != Other {
+				insert.end = offset
+			}
+			others.WriteString(t.Value)
+		} else {
+			if last == EOF || last.Type == Other {
+				insert = &insertion{start: offset}
+				insertions = append(insertions, insert)
+			}
+			insert.tokens = append(insert.tokens, t)
+		}
+		last = t
+		offset += len(t.Value)
+	}
+	// An insertion running to the end of the text is not closed by an "Other" token.
+	if insert != nil && last.Type != Other {
+		insert.end = offset
+	}
+	return others.String(), insertions
+}
+
+// align root tokens with the text they were lexed from.
+func (d *delegatingLexer) align(tokens []Token, text string) ([]Token, error) {
+	if d.alignment == DelegateUnchecked {
+		return tokens, nil
+	}
+	total := 0
+	for _, t := range tokens {
+		total += len(t.Value)
+	}
+	if total == len(text) {
+		return tokens, nil
+	}
+	if d.alignment == DelegateStrict {
+		return nil, fmt.Errorf("%s: root lexer emitted %d bytes for %d bytes of input", d.root.Config().Name, total, len(text))
+	}
+	return realign(tokens, text), nil
+}
+
+// realign root tokens by position so that they exactly reproduce text.
+//
+// Each token is matched against the text at the current position. Text that
+// the root lexer skipped is emitted as Text tokens, and tokens that do not
+// occur in the remaining text are dropped.
+func realign(tokens []Token, text string) []Token {
+	out := make([]Token, 0, len(tokens))
+	pos := 0
+	for _, t := range tokens {
+		if t.Value == "" {
+			continue
+		}
+		skip := strings.Index(text[pos:], t.Value)
+		if skip < 0 {
+			continue
+		}
+		if skip > 0 {
+			out = append(out, Token{Type: Text, Value: text[pos : pos+skip]})
+		}
+		out = append(out, t)
+		pos += skip + len(t.Value)
+	}
+	if pos < len(text) {
+		out = append(out, Token{Type: Text, Value: text[pos:]})
+	}
+	return out
+}
+
 func splitToken(t Token, offset int) (l Token, r Token) {
 	if t == EOF {
 		return EOF, EOF
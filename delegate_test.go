@@ -109,3 +109,96 @@ func TestDelegate(t *testing.T) {
 		})
 	}
 }
+
+func TestDelegateTrailingInsertion(t *testing.T) {
+	lang, root := makeDelegationTestLexers(t)
+	sources := []string{
+		`hello <? what ?>`,
+		`hello <? what ?> there <? what ?>`,
+		`<? what ?>`,
+	}
+	for _, source := range sources {
+		for _, alignment := range []DelegateAlignment{DelegateUnchecked, DelegateStrict} {
+			it, err := DelegatingLexerWithAlignment(root, lang, alignment).Tokenise(nil, source)
+			assert.NoError(t, err)
+			tokens := it.Tokens()
+			assert.Equal(t, Token{CommentPreproc, "?>"}, tokens[len(tokens)-1], source)
+			offset := 0
+			for _, token := range tokens {
+				assert.Equal(t, source[offset:offset+len(token.Value)], token.Value, source)
+				offset += len(token.Value)
+			}
+			assert.Equal(t, len(source), offset, source)
+		}
+
+		// Each insertion, including a trailing one, spans exactly its tokens.
+		tokens, err := Tokenise(Coalesce(lang), nil, source)
+		assert.NoError(t, err)
+		_, insertions := computeInsertions(tokens)
+		assert.NotEmpty(t, insertions, source)
+		for _, insert := range insertions {
+			assert.Equal(t, Stringify(insert.tokens...), source[insert.start:insert.end], source)
+		}
+		assert.Equal(t, len(source), insertions[len(insertions)-1].end, source)
+	}
+}
+
+func TestDelegateAlignment(t *testing.T) {
+	lang, root := makeDelegationTestLexers(t)
+	source := `hello world <? what ?> there`
+	// Drops whitespace, so emits fewer bytes than it was given.
+	short := RemappingLexer(root, func(t Token) []Token {
+		if t.Type == Whitespace {
+			return nil
+		}
+		return []Token{t}
+	})
+	// Duplicates names, so emits more bytes than it was given.
+	long := RemappingLexer(root, func(t Token) []Token {
+		if t.Type == Name {
+			return []Token{t, t}
+		}
+		return []Token{t}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		_, err := DelegatingLexerWithAlignment(short, lang, DelegateStrict).Tokenise(nil, source)
+		assert.Error(t, err)
+		_, err = DelegatingLexerWithAlignment(long, lang, DelegateStrict).Tokenise(nil, source)
+		assert.Error(t, err)
+		it, err := DelegatingLexerWithAlignment(root, lang, DelegateStrict).Tokenise(nil, source)
+		assert.NoError(t, err)
+		assert.Equal(t, source, Stringify(it.Tokens()...))
+	})
+
+	t.Run("LenientPad", func(t *testing.T) {
+		it, err := DelegatingLexerWithAlignment(short, lang, DelegateLenient).Tokenise(nil, source)
+		assert.NoError(t, err)
+		tokens := it.Tokens()
+		assert.Equal(t, source, Stringify(tokens...))
+		assert.Equal(t, Token{Keyword, "hello"}, tokens[0])
+	})
+
+	t.Run("LenientDropMiddle", func(t *testing.T) {
+		// Drops a name in the middle of the text.
+		dropWorld := RemappingLexer(root, func(t Token) []Token {
+			if t.Value == "world" {
+				return nil
+			}
+			return []Token{t}
+		})
+		it, err := DelegatingLexerWithAlignment(dropWorld, lang, DelegateLenient).Tokenise(nil, source)
+		assert.NoError(t, err)
+		tokens := it.Tokens()
+		assert.Equal(t, source, Stringify(tokens...))
+		assert.Contains(t, tokens, Token{Name, "there"})
+	})
+
+	t.Run("LenientTruncate", func(t *testing.T) {
+		it, err := DelegatingLexerWithAlignment(long, lang, DelegateLenient).Tokenise(nil, source)
+		assert.NoError(t, err)
+		tokens := it.Tokens()
+		assert.Equal(t, source, Stringify(tokens...))
+		assert.Contains(t, tokens, Token{Keyword, "what"})
+	})
+}
@@ -1,7 +1,10 @@
 package chroma
 
 import (
+	"encoding/xml"
 	"fmt"
+
+	"github.com/dlclark/regexp2"
 )
 
 // An Emitter takes group matches and returns tokens.
@@ -201,6 +204,86 @@ func Using(lexer string) Emitter {
 	return &usingEmitter{Lexer: lexer}
 }
 
+// UsingIf returns an Emitter that uses the referenced lexer for text matching
+// pattern, and otherwise falls back to emitter. Used within ByGroups it allows
+// a captured group to be highlighted as an embedded language, eg. SQL within
+// string literals:
+//
+//	{`(")([^"]*)(")`, ByGroups(String, UsingIf("SQL", `(?i)^\s*(select|insert|update|delete)\b`, String), String), nil}
+//
+// As with Using, the referenced lexer must be stored in the same LexerRegistry.
+//
+// An invalid pattern is reported when the lexer is first used.
+func UsingIf(lexer, pattern string, emitter Emitter) Emitter {
+	u := &usingIfEmitter{Lexer: lexer, Pattern: pattern, Emitters: Emitters{emitter}}
+	u.err = u.compile()
+	return u
+}
+
+type usingIfEmitter struct {
+	Lexer    string   `xml:"lexer,attr"`
+	Pattern  string   `xml:"pattern,attr"`
+	Emitters Emitters `xml:"emitters"`
+
+	re  *regexp2.Regexp
+	err error
+}
+
+func (u *usingIfEmitter) EmitterKind() string { return "usingif" }
+
+func (u *usingIfEmitter) compile() (err error) {
+	u.re, err = regexp2.Compile(u.Pattern, 0)
+	if err != nil {
+		return fmt.Errorf("invalid UsingIf(%q) pattern %q: %w", u.Lexer, u.Pattern, err)
+	}
+	return nil
+}
+
+func (u *usingIfEmitter) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain usingIfEmitter
+	if err := d.DecodeElement((*plain)(u), &start); err != nil {
+		return err
+	}
+	return u.compile()
+}
+
+func (u *usingIfEmitter) Emit(groups []string, state *LexerState) Iterator {
+	if u.err != nil {
+		panic(u.err)
+	}
+	ok, err := u.re.MatchString(groups[0])
+	if err != nil {
+		panic(fmt.Errorf("UsingIf(%q): %w", u.Lexer, err))
+	}
+	if !ok {
+		return u.Emitters[0].Emit(groups, state)
+	}
+	return (&usingEmitter{Lexer: u.Lexer}).Emit(groups, state)
+}
+
+// validateEmitter returns the first construction error in emitter or any
+// emitter nested within it.
+func validateEmitter(emitter Emitter) error {
+	var nested Emitters
+	switch emitter := emitter.(type) {
+	case *usingIfEmitter:
+		if emitter.err != nil {
+			return emitter.err
+		}
+		nested = emitter.Emitters
+	case *byGroupsEmitter:
+		nested = emitter.Emitters
+	case *usingByGroup:
+		nested = emitter.Emitters
+	}
+	for _, e := range nested {
+		if err := validateEmitter(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type usingSelfEmitter struct {
 	State string `xml:"state,attr"`
 }
@@ -219,3 +302,36 @@ func (u *usingSelfEmitter) Emit(groups []string, state *LexerState) Iterator {
 func UsingSelf(stateName string) Emitter {
 	return &usingSelfEmitter{stateName}
 }
+
+// AnnotateGroups wraps an Emitter for debugging rules, prefixing the value of
+// each emitted token with the index of the regex group it originated from, eg.
+// "[1:func]". If emitter is not created by ByGroups, tokens are annotated with
+// group 0, the whole match.
+//
+// This Emitter is not serialisable.
+func AnnotateGroups(emitter Emitter) Emitter {
+	return EmitterFunc(func(groups []string, state *LexerState) Iterator {
+		byGroups, ok := emitter.(*byGroupsEmitter)
+		if !ok || len(byGroups.Emitters) != len(groups)-1 {
+			return annotateGroup(0, emitter.Emit(groups, state))
+		}
+		iterators := make([]Iterator, 0, len(groups)-1)
+		for i, group := range groups[1:] {
+			if byGroups.Emitters[i] != nil {
+				iterators = append(iterators, annotateGroup(i+1, byGroups.Emitters[i].Emit([]string{group}, state)))
+			}
+		}
+		return Concaterator(iterators...)
+	})
+}
+
+func annotateGroup(group int, it Iterator) Iterator {
+	return func() Token {
+		t := it()
+		if t == EOF {
+			return t
+		}
+		t.Value = fmt.Sprintf("[%d:%s]", group, t.Value)
+		return t
+	}
+}
@@ -0,0 +1,26 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateGroups(t *testing.T) {
+	lexer := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`(func)(\s+)(\w+)(\()`, AnnotateGroups(ByGroups(Keyword, Whitespace, NameFunction, Punctuation)), nil},
+			{`\)`, AnnotateGroups(Punctuation), nil},
+		},
+	})
+	it, err := lexer.Tokenise(nil, "func main()")
+	require.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Keyword, "[1:func]"},
+		{Whitespace, "[2: ]"},
+		{NameFunction, "[3:main]"},
+		{Punctuation, "[4:(]"},
+		{Punctuation, "[0:)]"},
+	}, it.Tokens())
+}
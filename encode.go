@@ -0,0 +1,86 @@
+package chroma
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// EncodeTokens writes tokens from an Iterator to w in a compact binary format.
+//
+// Each token is encoded as a varint TokenType, followed by a uvarint length
+// prefixed value. Use DecodeTokens to read the stream back.
+func EncodeTokens(w io.Writer, it Iterator) error {
+	bw := bufio.NewWriter(w)
+	var header [binary.MaxVarintLen64 * 2]byte
+	for t := it(); t != EOF; t = it() {
+		n := binary.PutVarint(header[:], int64(t.Type))
+		n += binary.PutUvarint(header[n:], uint64(len(t.Value)))
+		if _, err := bw.Write(header[:n]); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(t.Value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// maxDecodedTokenLength is the longest token value DecodeTokens accepts.
+const maxDecodedTokenLength = math.MaxInt32
+
+// DecodeTokens returns an Iterator over tokens encoded with EncodeTokens, and
+// a function returning the error, if any, that ended the stream early.
+//
+// A malformed or unreadable stream ends the Iterator, rather than panicking.
+// Values are read incrementally, so a corrupt length prefix cannot cause a
+// large allocation up front.
+func DecodeTokens(r io.Reader) (Iterator, func() error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	var failed error
+	done := false
+	fail := func(err error) Token {
+		failed = err
+		done = true
+		return EOF
+	}
+	it := func() Token {
+		if done {
+			return EOF
+		}
+		ttype, err := binary.ReadVarint(br)
+		if errors.Is(err, io.EOF) {
+			done = true
+			return EOF
+		} else if err != nil {
+			return fail(fmt.Errorf("invalid token type: %w", err))
+		}
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return fail(fmt.Errorf("invalid token length: %w", unexpectedEOF(err)))
+		}
+		if size > maxDecodedTokenLength {
+			return fail(fmt.Errorf("invalid token length %d: exceeds maximum of %d", size, maxDecodedTokenLength))
+		}
+		value := &strings.Builder{}
+		if _, err := io.CopyN(value, br, int64(size)); err != nil {
+			return fail(fmt.Errorf("invalid token value: %w", unexpectedEOF(err)))
+		}
+		return Token{Type: TokenType(ttype), Value: value.String()}
+	}
+	return it, func() error { return failed }
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package chroma
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeTokens(t *testing.T) {
+	tokens := []Token{
+		{Keyword, "func"},
+		{Whitespace, " "},
+		{NameFunction, "main"},
+		{Error, "→"},
+		{Background, ""},
+		{Text, "\n"},
+	}
+	buf := &bytes.Buffer{}
+	err := EncodeTokens(buf, Literator(tokens...))
+	assert.NoError(t, err)
+	it, errf := DecodeTokens(buf)
+	assert.Equal(t, tokens, it.Tokens())
+	assert.NoError(t, errf())
+}
+
+func TestDecodeTokensTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := EncodeTokens(buf, Literator(Token{Keyword, "func"}, Token{Text, "x"}))
+	assert.NoError(t, err)
+	it, errf := DecodeTokens(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Equal(t, []Token{{Keyword, "func"}}, it.Tokens())
+	assert.ErrorIs(t, errf(), io.ErrUnexpectedEOF)
+	assert.Equal(t, EOF, it())
+}
+
+func TestDecodeTokensOversizedLength(t *testing.T) {
+	header := make([]byte, binary.MaxVarintLen64*2)
+	n := binary.PutVarint(header, int64(Keyword))
+	n += binary.PutUvarint(header[n:], 1<<40)
+	it, errf := DecodeTokens(bytes.NewReader(header[:n]))
+	assert.Equal(t, EOF, it())
+	assert.EqualError(t, errf(), "invalid token length 1099511627776: exceeds maximum of 2147483647")
+
+	// A length within the limit but beyond the end of the stream is not
+	// allocated up front.
+	n = binary.PutVarint(header, int64(Keyword))
+	n += binary.PutUvarint(header[n:], maxDecodedTokenLength)
+	it, errf = DecodeTokens(bytes.NewReader(append(header[:n], "abc"...)))
+	assert.Equal(t, EOF, it())
+	assert.ErrorIs(t, errf(), io.ErrUnexpectedEOF)
+}
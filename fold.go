@@ -0,0 +1,180 @@
+package chroma
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/regexp2"
+)
+
+// A FoldRegion is a foldable span of source, from StartLine to EndLine
+// inclusive. Lines are numbered from 1.
+type FoldRegion struct {
+	StartLine int
+	EndLine   int
+}
+
+// ComputeFoldRegions returns the fold regions of tokens, as described by the
+// Fold hints of config.
+//
+// If config has no Fold hints, regions are delimited by brackets within
+// Punctuation tokens. Regions are returned ordered by start line, outermost
+// first, and only regions spanning more than one line are included.
+func ComputeFoldRegions(config *Config, tokens []Token) []FoldRegion {
+	fold := &FoldConfig{}
+	if config != nil && config.Fold != nil {
+		fold = config.Fold
+	}
+	lines := SplitTokensIntoLines(tokens)
+	var regions []FoldRegion
+	if fold.Indent {
+		regions = indentFoldRegions(lines)
+	} else {
+		regions = bracketFoldRegions(lines)
+	}
+	regions = append(regions, markerFoldRegions(fold.Markers, lines)...)
+	sort.SliceStable(regions, func(i, j int) bool {
+		if regions[i].StartLine != regions[j].StartLine {
+			return regions[i].StartLine < regions[j].StartLine
+		}
+		return regions[i].EndLine > regions[j].EndLine
+	})
+	return regions
+}
+
+func bracketFoldRegions(lines [][]Token) []FoldRegion {
+	type open struct {
+		line    int
+		closing byte
+	}
+	regions := []FoldRegion{}
+	stack := []open{}
+	for i, line := range lines {
+		for _, token := range line {
+			if !token.Type.InCategory(Punctuation) {
+				continue
+			}
+			for j := 0; j < len(token.Value); j++ {
+				c := token.Value[j]
+				if closing, ok := bracketPairs[c]; ok {
+					stack = append(stack, open{i + 1, closing})
+					continue
+				}
+				if len(stack) == 0 || stack[len(stack)-1].closing != c {
+					continue
+				}
+				start := stack[len(stack)-1].line
+				stack = stack[:len(stack)-1]
+				if i+1 > start {
+					regions = append(regions, FoldRegion{start, i + 1})
+				}
+			}
+		}
+	}
+	return regions
+}
+
+func indentFoldRegions(lines [][]Token) []FoldRegion {
+	// Indentation of each line, or -1 if it is blank.
+	indents := make([]int, len(lines))
+	for i, line := range lines {
+		text := ""
+		for _, token := range line {
+			text += token.Value
+		}
+		text = strings.TrimRight(text, "\r\n")
+		trimmed := strings.TrimLeft(text, " \t")
+		if trimmed == "" {
+			indents[i] = -1
+		} else {
+			indents[i] = len(text) - len(trimmed)
+		}
+	}
+	regions := []FoldRegion{}
+	for i, indent := range indents {
+		if indent < 0 {
+			continue
+		}
+		end := i
+		for j := i + 1; j < len(indents); j++ {
+			if indents[j] < 0 {
+				continue
+			}
+			if indents[j] <= indent {
+				break
+			}
+			end = j
+		}
+		if end > i {
+			regions = append(regions, FoldRegion{i + 1, end + 1})
+		}
+	}
+	return regions
+}
+
+// foldMarkerRegexes caches compiled fold marker patterns by pattern.
+var foldMarkerRegexes sync.Map
+
+// compileFoldMarker returns the compiled regex for a fold marker pattern.
+func compileFoldMarker(pattern string) (*regexp2.Regexp, error) {
+	if re, ok := foldMarkerRegexes.Load(pattern); ok {
+		return re.(*regexp2.Regexp), nil
+	}
+	re, err := regexp2.Compile(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	foldMarkerRegexes.Store(pattern, re)
+	return re, nil
+}
+
+// validateFold compiles the fold marker patterns of config, so that they are
+// ready for use by ComputeFoldRegions.
+func validateFold(config *Config) error {
+	if config.Fold == nil {
+		return nil
+	}
+	for _, marker := range config.Fold.Markers {
+		for _, pattern := range []string{marker.Start, marker.End} {
+			if _, err := compileFoldMarker(pattern); err != nil {
+				return fmt.Errorf("%s: invalid fold marker pattern %q: %w", config.Name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+func markerFoldRegions(markers []FoldMarkerConfig, lines [][]Token) []FoldRegion {
+	regions := []FoldRegion{}
+	for _, marker := range markers {
+		// Patterns are validated when the Lexer is created, so invalid markers
+		// can only come from a Config built by hand, and are ignored.
+		start, err := compileFoldMarker(marker.Start)
+		if err != nil {
+			continue
+		}
+		end, err := compileFoldMarker(marker.End)
+		if err != nil {
+			continue
+		}
+		stack := []int{}
+		for i, line := range lines {
+			for _, token := range line {
+				if !token.Type.InCategory(Comment) {
+					continue
+				}
+				if ok, _ := end.MatchString(token.Value); ok {
+					if len(stack) > 0 {
+						regions = append(regions, FoldRegion{stack[len(stack)-1], i + 1})
+						stack = stack[:len(stack)-1]
+					}
+				} else if ok, _ := start.MatchString(token.Value); ok {
+					stack = append(stack, i+1)
+				}
+			}
+		}
+	}
+	return regions
+}
@@ -0,0 +1,78 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFoldRegions(t *testing.T) {
+	t.Run("Brackets", func(t *testing.T) {
+		tokens := []Token{
+			{Keyword, "func"}, {Whitespace, " "}, {Name, "f"}, {Punctuation, "()"}, {Whitespace, " "}, {Punctuation, "{"}, {Whitespace, "\n"},
+			{Name, "a"}, {Punctuation, "("}, {String, `"{"`}, {Punctuation, ")"}, {Whitespace, "\n"},
+			{Punctuation, "}"}, {Whitespace, "\n"},
+		}
+		assert.Equal(t, []FoldRegion{{1, 3}}, ComputeFoldRegions(nil, tokens))
+	})
+
+	t.Run("Indent", func(t *testing.T) {
+		tokens := []Token{
+			{Text, "def f():\n"},
+			{Text, "    if x:\n"},
+			{Text, "        y()\n"},
+			{Text, "\n"},
+			{Text, "    z()\n"},
+			{Text, "w()\n"},
+		}
+		config := &Config{Fold: &FoldConfig{Indent: true}}
+		assert.Equal(t, []FoldRegion{{1, 5}, {2, 3}}, ComputeFoldRegions(config, tokens))
+	})
+
+	t.Run("Markers", func(t *testing.T) {
+		lexer := MustNewLexer(&Config{
+			Name: "RegionComments",
+			Fold: &FoldConfig{
+				Markers: []FoldMarkerConfig{{Start: `^//\s*region\b`, End: `^//\s*endregion\b`}},
+			},
+		}, func() Rules {
+			return Rules{
+				"root": {
+					{`//[^\n]*`, CommentSingle, nil},
+					{`[{}()]`, Punctuation, nil},
+					{`\w+`, Name, nil},
+					{`\s+`, Whitespace, nil},
+				},
+			}
+		})
+		source := "// region Outer\n" +
+			"a\n" +
+			"// region Inner\n" +
+			"b {\n" +
+			"}\n" +
+			"// endregion\n" +
+			"// endregion\n"
+		it, err := lexer.Tokenise(nil, source)
+		require.NoError(t, err)
+		regions := ComputeFoldRegions(lexer.Config(), it.Tokens())
+		assert.Equal(t, []FoldRegion{{1, 7}, {3, 6}, {4, 5}}, regions)
+	})
+
+	t.Run("InvalidMarker", func(t *testing.T) {
+		config := &Config{
+			Name: "Invalid",
+			Fold: &FoldConfig{Markers: []FoldMarkerConfig{{Start: `(unclosed`, End: `end`}}},
+		}
+		_, err := NewLexer(config, func() Rules { return Rules{"root": {{`.`, Text, nil}}} })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "(unclosed")
+
+		_, err = Unmarshal([]byte(`<lexer><config><name>Invalid</name><fold><marker start="(unclosed" end="end"/></fold></config>` +
+			`<rules><state name="root"><rule pattern="."><token type="Text"/></rule></state></rules></lexer>`))
+		require.Error(t, err)
+
+		// A Config built by hand is not validated, but does not panic.
+		assert.Equal(t, []FoldRegion{}, ComputeFoldRegions(config, []Token{{CommentSingle, "// x\n"}}))
+	})
+}
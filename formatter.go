@@ -41,3 +41,14 @@ func (r recoveringFormatter) Format(w io.Writer, s *Style, it Iterator) (err err
 
 // RecoveringFormatter wraps a formatter with panic recovery.
 func RecoveringFormatter(formatter Formatter) Formatter { return recoveringFormatter{formatter} }
+
+// FormatCached formats tokens, eg. a slice cached from an earlier call to
+// Tokenise, with formatter without tokenising again.
+//
+// The output is identical to formatting a fresh Iterator from the same lexer,
+// provided the tokens were produced by exactly that lexer, including any
+// Coalesce wrapper: formatters render tokens as given, so coalesced and
+// uncoalesced tokens of the same text may render differently.
+func FormatCached(w io.Writer, formatter Formatter, style *Style, tokens []Token) error {
+	return formatter.Format(w, style, Literator(tokens...))
+}
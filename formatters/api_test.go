@@ -0,0 +1,154 @@
+package formatters
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestPreserveEOF(t *testing.T) {
+	options := &chroma.TokeniseOptions{State: "root", EnsureLF: true, PreserveEOF: true}
+	format := func(f chroma.Formatter, source string) string {
+		it, err := lexers.Get("go").Tokenise(options, source)
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		require.NoError(t, f.Format(buf, styles.Fallback, it))
+		return buf.String()
+	}
+
+	for _, source := range []string{"x := 1", "x := 1\n", "x := 1\ny := 2"} {
+		assert.Equal(t, source, format(NoOp, source))
+	}
+
+	f := html.New(html.WithClasses(true), html.PreventSurroundingPre(true))
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="nx">x</span></span></span>`, format(f, "x"))
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="nx">x</span>
+</span></span>`, format(f, "x\n"))
+}
+
+func TestFromExternal(t *testing.T) {
+	// eg. spans produced by tree-sitter.
+	source := "fn main() {}\n"
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "fn"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.NameFunction, Value: "main"},
+		{Type: chroma.Punctuation, Value: "() {}"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	for _, name := range []string{"html", "terminal256", "json", "noop"} {
+		it, err := chroma.FromExternal(source, tokens)
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		err = Get(name).Format(buf, styles.Fallback, it)
+		require.NoError(t, err, name)
+		assert.Contains(t, buf.String(), "main", name)
+	}
+	it, err := chroma.FromExternal(source, tokens)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	err = html.New(html.WithClasses(true), html.PreventSurroundingPre(true)).Format(buf, styles.Fallback, it)
+	require.NoError(t, err)
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="k">fn</span> <span class="nf">main</span><span class="p">() {}</span>
+</span></span>`, buf.String())
+
+	// Invalid tokens are reported before anything is formatted.
+	for _, test := range []struct {
+		name   string
+		source string
+		tokens []chroma.Token
+		err    string
+	}{
+		{"Empty", "fnx", []chroma.Token{{Type: chroma.Keyword, Value: "fn"}, {}, {Type: chroma.Text, Value: "x"}}, "external token 1 (EOFType) is empty"},
+		{"Gap", source, []chroma.Token{tokens[0], tokens[2]}, `external token 1 (NameFunction) "main" does not match the source at offset 2`},
+		{"Overlap", source, []chroma.Token{tokens[0], {Type: chroma.Text, Value: "n main() {}\n"}}, `external token 1 (Text) "n main() {}\n" does not match the source at offset 2`},
+		{"Short", source, tokens[:3], "external tokens end at offset 7 of 13"},
+		{"InvalidUTF8", "\xe2\x82", []chroma.Token{{Type: chroma.Text, Value: "\xe2\x82"}}, `external token 0 (Text) is not valid UTF-8: "\xe2\x82"`},
+	} {
+		it, err := chroma.FromExternal(test.source, test.tokens)
+		assert.Nil(t, it, test.name)
+		assert.EqualError(t, err, test.err, test.name)
+	}
+}
+
+func TestCustomLexer(t *testing.T) {
+	// A hand-written scanner for "key = 123 # comment" lines.
+	scan := func(options *chroma.TokeniseOptions, text string) (chroma.Iterator, error) {
+		tokens := []chroma.Token{}
+		for len(text) > 0 {
+			n, tt := 1, chroma.Text
+			switch c := text[0]; {
+			case c == '#':
+				n, tt = strings.IndexByte(text+"\n", '\n'), chroma.Comment
+			case c >= '0' && c <= '9':
+				for n < len(text) && text[n] >= '0' && text[n] <= '9' {
+					n++
+				}
+				tt = chroma.Number
+			case c >= 'a' && c <= 'z':
+				for n < len(text) && text[n] >= 'a' && text[n] <= 'z' {
+					n++
+				}
+				tt = chroma.Name
+			case c == '=':
+				tt = chroma.Operator
+			}
+			tokens = append(tokens, chroma.Token{Type: tt, Value: text[:n]})
+			text = text[n:]
+		}
+		return chroma.Literator(tokens...), nil
+	}
+	registry := chroma.NewLexerRegistry()
+	registry.Register(chroma.NewCustomLexer(&chroma.Config{Name: "keys", Filenames: []string{"*.keys"}}, scan))
+	lexer := registry.Match("a.keys")
+	require.NotNil(t, lexer)
+
+	source := "size = 42 # bytes\n"
+	it, err := lexer.Tokenise(nil, source)
+	require.NoError(t, err)
+	tokens := it.Tokens()
+	for _, name := range Names() {
+		err := Get(name).Format(&bytes.Buffer{}, styles.Fallback, chroma.Literator(tokens...))
+		require.NoError(t, err, name)
+	}
+	buf := &bytes.Buffer{}
+	err = html.New(html.WithClasses(true), html.PreventSurroundingPre(true)).Format(buf, styles.Fallback, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="n">size</span> <span class="o">=</span> <span class="m">42</span> <span class="c"># bytes</span>
+</span></span>`, buf.String())
+
+	// Custom lexers report original lengths unchanged.
+	tokens, lengths, err := chroma.TokeniseWithOriginalLen(lexer, nil, source)
+	require.NoError(t, err)
+	total := 0
+	for i := range tokens {
+		total += lengths.OriginalLen(&tokens[i])
+	}
+	assert.Equal(t, len(source), total)
+}
+
+func TestFormatCached(t *testing.T) {
+	source := "package main\n\n// Comment\n// continued.\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+	style := styles.Get("monokai")
+	for _, lexer := range []chroma.Lexer{lexers.Get("go"), chroma.Coalesce(lexers.Get("go"))} {
+		cached, err := chroma.Tokenise(lexer, nil, source)
+		require.NoError(t, err)
+		for _, formatter := range []chroma.Formatter{html.New(), html.New(html.WithClasses(true), html.WithLineNumbers(true)), TTY256, Tokens} {
+			it, err := lexer.Tokenise(nil, source)
+			require.NoError(t, err)
+			fresh := &bytes.Buffer{}
+			require.NoError(t, formatter.Format(fresh, style, it))
+			buf := &bytes.Buffer{}
+			require.NoError(t, chroma.FormatCached(buf, formatter, style, cached))
+			assert.Equal(t, fresh.String(), buf.String())
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package formatters
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/formatters/svg"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestLanguageBadge(t *testing.T) {
+	source := "package main\n\nfunc main() {}\n"
+	colour, background := chroma.MustParseColour("#ffffff"), chroma.MustParseColour("#007d9c")
+	tests := []struct {
+		name      string
+		formatter chroma.Formatter
+	}{
+		{"badge.html", html.New(html.WithLanguageBadge("Go"), html.LanguageBadgeStyle("bottom-left", colour, background))},
+		{"badge-classes.html", html.New(html.WithClasses(true), html.WithLanguageBadge("Go"))},
+		{"badge.svg", svg.New(svg.WithLanguageBadge("Go"), svg.LanguageBadgeStyle("top-right", colour, background))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			it, err := lexers.Get("go").Tokenise(nil, source)
+			require.NoError(t, err)
+			buf := &bytes.Buffer{}
+			require.NoError(t, test.formatter.Format(buf, styles.Get("monokai"), it))
+
+			golden := "testdata/" + test.name + ".golden"
+			if os.Getenv("RECORD") == "true" {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0600))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package formatters
+
+import (
+	"io"
+	"sort"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// A Decoration overrides the style of the [Start, End) byte range of the source.
+//
+// Attributes set in Style replace those of the style beneath it, while unset
+// attributes are inherited. If Style.NoInherit is set the decoration replaces
+// the underlying style entirely.
+type Decoration struct {
+	Start, End int
+	Style      chroma.StyleEntry
+}
+
+// A DecorationProvider returns the decorations to layer over the given tokens,
+// eg. search matches, errors or a selection.
+type DecorationProvider func(tokens []chroma.Token) []Decoration
+
+// compositeTypeBase is the first TokenType allocated by Composite for blended
+// style entries.
+const compositeTypeBase chroma.TokenType = 1 << 24
+
+// Composite wraps formatter, layering the decorations from each provider over
+// the base Style passed to Format.
+//
+// Providers are applied in order, as are the decorations each returns, so
+// later decorations take precedence over earlier ones. Tokens are split at
+// decoration boundaries, and each distinct blended StyleEntry is assigned a
+// synthesised TokenType in a style derived from the base. The wrapped
+// formatter must therefore resolve styles by token type, as the terminal
+// formatters and the HTML formatter do. With classes, the HTML formatter styles
+// synthesised types inline.
+func Composite(formatter chroma.Formatter, providers ...DecorationProvider) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		tokens := it.Tokens()
+		layers := make([][]Decoration, len(providers))
+		cuts := []int{}
+		for i, provider := range providers {
+			layers[i] = provider(tokens)
+			for _, d := range layers[i] {
+				cuts = append(cuts, d.Start, d.End)
+			}
+		}
+		sort.Ints(cuts)
+
+		builder := style.Builder()
+		types := map[chroma.StyleEntry]chroma.TokenType{}
+		out := make([]chroma.Token, 0, len(tokens))
+		offset := 0
+		for _, t := range tokens {
+			start, end := offset, offset+len(t.Value)
+			offset = end
+			base := style.Get(t.Type)
+			for pos := start; pos < end; {
+				next := end
+				if i := sort.SearchInts(cuts, pos+1); i < len(cuts) && cuts[i] < end {
+					next = cuts[i]
+				}
+				entry := base
+				for _, layer := range layers {
+					for _, d := range layer {
+						if d.Start <= pos && pos < d.End {
+							entry = d.Style.Inherit(entry)
+						}
+					}
+				}
+				segment := chroma.Token{Type: t.Type, Value: t.Value[pos-start : next-start]}
+				if entry != base {
+					tt, ok := types[entry]
+					if !ok {
+						tt = compositeTypeBase + chroma.TokenType(len(types))
+						types[entry] = tt
+						builder.AddEntry(tt, entry)
+					}
+					segment.Type = tt
+				}
+				out = append(out, segment)
+				pos = next
+			}
+		}
+		composite, err := builder.Build()
+		if err != nil {
+			return err
+		}
+		return formatter.Format(w, composite, chroma.Literator(out...))
+	})
+}
@@ -0,0 +1,75 @@
+package formatters
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+)
+
+func TestComposite(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background: "#000000 bg:#ffffff",
+		chroma.Keyword:    "bold #0000ff",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "func"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.NameFunction, Value: "main"},
+	}
+	search := func([]chroma.Token) []Decoration {
+		return []Decoration{{Start: 2, End: 7, Style: chroma.StyleEntry{Background: chroma.MustParseColour("#ffff00")}}}
+	}
+	errors := func([]chroma.Token) []Decoration {
+		return []Decoration{{Start: 5, End: 9, Style: chroma.StyleEntry{Colour: chroma.MustParseColour("#ff0000"), Underline: chroma.Yes}}}
+	}
+	record := chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		for token := it(); token != chroma.EOF; token = it() {
+			fmt.Fprintf(w, "%q: %s\n", token.Value, style.Get(token.Type))
+		}
+		return nil
+	})
+
+	buf := &bytes.Buffer{}
+	err := Composite(record, search, errors).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	expected := `"fu": bold #0000ff bg:#ffffff
+"nc": bold #0000ff bg:#ffff00
+" ": #000000 bg:#ffff00
+"ma": underline #ff0000 bg:#ffff00
+"in": underline #ff0000 bg:#ffffff
+`
+	assert.Equal(t, expected, buf.String())
+
+	// Later overlays take precedence where attributes conflict.
+	buf.Reset()
+	recolour := func([]chroma.Token) []Decoration {
+		return []Decoration{{Start: 0, End: 7, Style: chroma.StyleEntry{Colour: chroma.MustParseColour("#00ff00")}}}
+	}
+	err = Composite(record, errors, recolour, search).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	expected = `"fu": bold #00ff00 bg:#ffffff
+"nc": bold #00ff00 bg:#ffff00
+" ": #00ff00 bg:#ffff00
+"ma": underline #00ff00 bg:#ffff00
+"in": underline #ff0000 bg:#ffffff
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	err = Composite(html.New(html.PreventSurroundingPre(true)), search).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	assert.Equal(t, `<span style="display:flex;"><span><span style="color:#00f;font-weight:bold">fu</span><span style="color:#00f;background-color:#ff0;font-weight:bold">nc</span><span style="background-color:#ff0"> </span><span style="background-color:#ff0">ma</span>in</span></span>`, buf.String())
+
+	// Blended entries have no class, so are styled inline.
+	buf.Reset()
+	err = Composite(html.New(html.PreventSurroundingPre(true), html.WithClasses(true)), search).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="k">fu</span><span style="color: #0000ff; background-color: #ffff00; font-weight: bold">nc</span><span style="background-color: #ffff00"> </span><span style="background-color: #ffff00">ma</span><span class="nf">in</span></span></span>`, buf.String())
+}
@@ -0,0 +1,42 @@
+package formatters
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// CSV formatter outputs one row of type, value, length and line per token.
+var CSV = Register("csv", NewCSV(true))
+
+// NewCSV creates a formatter that outputs tokens as CSV rows of type, value
+// and length in bytes. If lineNumbers is true, a column with the 1-based line
+// on which each token starts is also included.
+func NewCSV(lineNumbers bool) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, s *chroma.Style, it chroma.Iterator) error {
+		cw := csv.NewWriter(w)
+		header := []string{"type", "value", "length"}
+		if lineNumbers {
+			header = append(header, "line")
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		line := 1
+		for t := it(); t != chroma.EOF; t = it() {
+			row := []string{t.Type.String(), t.Value, strconv.Itoa(len(t.Value))}
+			if lineNumbers {
+				row = append(row, strconv.Itoa(line))
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+			line += strings.Count(t.Value, "\n")
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+}
@@ -0,0 +1,39 @@
+package formatters
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestCSVFormatter(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.Name, Value: "f"},
+		{Type: chroma.String, Value: `"a, b"`},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.Comment, Value: "// c"},
+	}
+	buf := &bytes.Buffer{}
+	err := Get("csv").Format(buf, nil, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	assert.Equal(t, "type,value,length,line\n"+
+		"Name,f,1,1\n"+
+		"LiteralString,\"\"\"a, b\"\"\",6,1\n"+
+		"Text,\"\n\",1,1\n"+
+		"Comment,// c,4,2\n", buf.String())
+
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LiteralString", `"a, b"`, "6", "1"}, records[2])
+	assert.Equal(t, []string{"Text", "\n", "1", "1"}, records[3])
+
+	buf.Reset()
+	err = NewCSV(false).Format(buf, nil, chroma.Literator(tokens[0]))
+	require.NoError(t, err)
+	assert.Equal(t, "type,value,length\nName,f,1\n", buf.String())
+}
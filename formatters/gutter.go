@@ -0,0 +1,42 @@
+package formatters
+
+import (
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// gutterMarkerType is the TokenType HighlightGutterMarker styles its marker
+// with. It is distinct from the types synthesised by Composite, so that the two
+// can be combined.
+const gutterMarkerType = compositeTypeBase - 1
+
+// HighlightGutterMarker wraps a terminal formatter, prefixing each line with a
+// one column gutter in which lines within ranges are marked with a bar of the
+// given colour. Ranges are 1-based and inclusive, as with html.HighlightLines.
+//
+// The marker is styled with a synthesised TokenType and written by the wrapped
+// formatter, so its colour is mapped to the formatter's palette.
+func HighlightGutterMarker(formatter chroma.Formatter, ranges [][2]int, colour chroma.Colour) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		builder := style.Builder()
+		builder.AddEntry(gutterMarkerType, chroma.StyleEntry{Colour: colour, NoInherit: true})
+		marked, err := builder.Build()
+		if err != nil {
+			return err
+		}
+		var out []chroma.Token
+		for i, line := range chroma.SplitTokensIntoLines(it.Tokens()) {
+			gutter := chroma.Token{Type: chroma.Text, Value: " "}
+			for _, r := range ranges {
+				if i+1 >= r[0] && i+1 <= r[1] {
+					gutter = chroma.Token{Type: gutterMarkerType, Value: "▌"}
+					break
+				}
+			}
+			out = append(out, gutter)
+			out = append(out, line...)
+		}
+		return formatter.Format(w, marked, chroma.Literator(out...))
+	})
+}
@@ -0,0 +1,47 @@
+package formatters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestHighlightGutterMarker(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "one"},
+		{Type: chroma.Text, Value: "\ntwo\nthree\n"},
+	}
+	red := chroma.MustParseColour("#ff0000")
+	f := HighlightGutterMarker(NoOp, [][2]int{{2, 3}}, red)
+	buf := &bytes.Buffer{}
+	err := f.Format(buf, styles.Fallback, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	assert.Equal(t, " one\n▌two\n▌three\n", buf.String())
+
+	// The marker is quantised to the wrapped formatter's palette.
+	style := chroma.MustNewStyle("plain", chroma.StyleEntries{})
+	for _, test := range []struct {
+		name      string
+		formatter chroma.Formatter
+		marker    string
+	}{
+		{"TTY8", TTY8, "\033[1m\033[31m▌\033[0m"},
+		{"TTY16", TTY16, "\033[91m▌\033[0m"},
+		{"TTY256", TTY256, "\033[38;5;196m▌\033[0m"},
+		{"TTY16m", TTY16m, "\033[38;2;255;0;0m▌\033[0m"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := HighlightGutterMarker(test.formatter, [][2]int{{2, 2}}, red).Format(buf, style, chroma.Literator(
+				chroma.Token{Type: chroma.Text, Value: "one\ntwo\n"},
+			))
+			require.NoError(t, err)
+			assert.Equal(t, " one\n"+test.marker+"two\n", buf.String())
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package formatters
+
+import (
+	"github.com/alecthomas/chroma/v2"
+)
+
+// HighlightLines wraps formatter, layering entry over the lines within ranges,
+// for formatters such as the terminal formatters that do not support
+// highlighting lines themselves. Ranges are 1-based and inclusive, as with
+// html.HighlightLines.
+//
+// Tokens spanning several lines, eg. block comments, are split at line
+// boundaries so that only the portion within a highlighted line is
+// highlighted. Newlines themselves are not highlighted.
+func HighlightLines(formatter chroma.Formatter, ranges [][2]int, entry chroma.StyleEntry) chroma.Formatter {
+	return Composite(formatter, func(tokens []chroma.Token) []Decoration {
+		decorations := []Decoration{}
+		line, start, offset := 1, 0, 0
+		flush := func(end int) {
+			for _, r := range ranges {
+				if line >= r[0] && line <= r[1] && end > start {
+					decorations = append(decorations, Decoration{Start: start, End: end, Style: entry})
+					break
+				}
+			}
+		}
+		for _, token := range tokens {
+			for i := 0; i < len(token.Value); i++ {
+				if token.Value[i] == '\n' {
+					flush(offset + i)
+					line++
+					start = offset + i + 1
+				}
+			}
+			offset += len(token.Value)
+		}
+		flush(offset)
+		return decorations
+	})
+}
@@ -0,0 +1,44 @@
+package formatters
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestHighlightLines(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background: "#000000 bg:#ffffff",
+		chroma.Comment:    "#888888",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "x"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.CommentMultiline, Value: "/* one\ntwo\nthree */"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	highlight := chroma.StyleEntry{Background: chroma.MustParseColour("#ffff00")}
+	buf := &bytes.Buffer{}
+	record := chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		for token := it(); token != chroma.EOF; token = it() {
+			fmt.Fprintf(w, "%q: %s\n", token.Value, style.Get(token.Type))
+		}
+		return nil
+	})
+	err := HighlightLines(record, [][2]int{{2, 2}}, highlight).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	// Only the portion of the comment on line 2 is highlighted.
+	assert.Equal(t, `"x": #000000 bg:#ffffff
+" ": #000000 bg:#ffffff
+"/* one\n": #888888 bg:#ffffff
+"two": #888888 bg:#ffff00
+"\nthree */": #888888 bg:#ffffff
+"\n": #000000 bg:#ffffff
+`, buf.String())
+}
@@ -2,10 +2,13 @@ package html
 
 import (
 	"fmt"
+	"hash/fnv"
 	"html"
 	"io"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/alecthomas/chroma/v2"
 )
@@ -46,6 +49,20 @@ func WithPreWrapper(wrapper PreWrapper) Option {
 	}
 }
 
+// WithPreCode controls whether highlighted code is wrapped in <pre><code>...</code></pre>,
+// as recommended for semantic HTML, or in a bare <pre>. Defaults to true.
+//
+// Line numbers rendered in a separate table cell are never wrapped in <code>.
+func WithPreCode(b bool) Option {
+	return func(f *Formatter) {
+		if b {
+			f.preWrapper = defaultPreWrapper
+		} else {
+			f.preWrapper = preOnlyPreWrapper
+		}
+	}
+}
+
 // WrapLongLines wraps long lines.
 func WrapLongLines(b bool) Option {
 	return func(f *Formatter) {
@@ -68,6 +85,14 @@ func LineNumbersInTable(b bool) Option {
 	}
 }
 
+// WithLineNumbersRight will, when combined with WithLineNumbers, place the line
+// numbers after the code rather than before it.
+func WithLineNumbersRight(b bool) Option {
+	return func(f *Formatter) {
+		f.lineNumbersRight = b
+	}
+}
+
 // LinkableLineNumbers decorates the line numbers HTML elements with an "id"
 // attribute so they can be linked.
 func LinkableLineNumbers(b bool, prefix string) Option {
@@ -94,11 +119,210 @@ func BaseLineNumber(n int) Option {
 	}
 }
 
+// MaxLines limits the output to the first n lines, followed by a notice
+// summarising how many lines were omitted. A value <= 0 disables truncation.
+func MaxLines(n int) Option {
+	return func(f *Formatter) {
+		f.maxLines = n
+	}
+}
+
+// WithCursor renders a cursor marker over the character at the given byte
+// offset in the source, using the Cursor style. A negative offset disables
+// the cursor.
+func WithCursor(offset int) Option {
+	return func(f *Formatter) {
+		f.cursor = offset
+	}
+}
+
+// InlineCode renders a single line of code as styled spans inside a <code>
+// element, suitable for embedding in a paragraph of prose.
+//
+// No line wrappers, line numbers or <pre> are emitted. Trailing newlines are
+// stripped and formatting fails if the code contains any other newlines.
+func InlineCode(b bool) Option {
+	return func(f *Formatter) {
+		f.inlineCode = b
+	}
+}
+
+// MergeSpans merges adjacent tokens with identical styles into a single span,
+// reducing the number of elements in the output.
+func MergeSpans(b bool) Option {
+	return func(f *Formatter) {
+		f.mergeSpans = b
+	}
+}
+
+// SingleElementLines renders the code of each line as plain text within a
+// single element, keeping token colours with an inline gradient of colour runs
+// measured in ch units rather than a span per token. This trades CSS size for
+// element count.
+//
+// Only colours are kept, and the runs only line up in a monospace font. The
+// cursor, indent guides, AlignTabs and MergeSpans do not apply to such lines.
+func SingleElementLines(b bool) Option {
+	return func(f *Formatter) {
+		f.singleElementLines = b
+	}
+}
+
+// AlignTabs renders each tab as a span whose width is computed to reach the
+// next tab stop, every TabWidth columns, so that tabular code stays aligned
+// even where the tab-size CSS emitted by TabWidth is not supported.
+func AlignTabs(b bool) Option { return func(f *Formatter) { f.alignTabs = b } }
+
+// IndentGuides draws a faint vertical guide at every width columns of each
+// line's leading whitespace, in the given CSS colour. Tabs are expanded
+// according to TabWidth. A width <= 0 disables guides.
+func IndentGuides(width int, colour string) Option {
+	return func(f *Formatter) {
+		f.indentGuideWidth = width
+		f.indentGuideColour = colour
+	}
+}
+
+// HighlightGutterMarker marks lines selected by HighlightLines with a bar of
+// the given CSS colour in the line number column, instead of highlighting the
+// full line. Without line numbers the bar is drawn at the start of the line.
+func HighlightGutterMarker(colour string) Option {
+	return func(f *Formatter) {
+		f.gutterMarkerColour = colour
+	}
+}
+
+// WithAriaLabels adds an aria-label attribute, eg. "keyword", to tokens in
+// each of the given types, sub-categories or categories, so that screen
+// readers can announce token kinds.
+func WithAriaLabels(types ...chroma.TokenType) Option {
+	return func(f *Formatter) {
+		f.ariaLabels = map[chroma.TokenType]string{}
+		for _, tt := range types {
+			f.ariaLabels[tt] = ariaLabel(tt)
+		}
+	}
+}
+
+// WithLanguage records the name of the language being highlighted in a
+// data-lang attribute on the wrapping element, eg. <pre data-lang="go">.
+func WithLanguage(lang string) Option {
+	return func(f *Formatter) {
+		f.lang = lang
+	}
+}
+
+// ContextView renders the target range of lines, as 1-based line numbers
+// inclusive, in full colour, surrounded by up to before and after lines of
+// dimmed context. Lines beyond the context are collapsed into a notice of how
+// many were omitted. A negative before or after includes all preceding or
+// following lines.
+func ContextView(target [2]int, before, after int) Option {
+	return func(f *Formatter) {
+		f.contextView = &contextView{target: target, before: before, after: after}
+	}
+}
+
+// WithValueRewriter replaces the value of each token with the result of
+// rewrite before formatting, eg. to redact secrets. Token types are preserved.
+//
+// As rewriting may change the length of the text, byte offsets given to other
+// options, eg. WithCursor, refer to the rewritten text rather than the source.
+// Likewise, line numbers are affected if newlines are added or removed.
+func WithValueRewriter(rewrite func(chroma.Token) string) Option {
+	return func(f *Formatter) {
+		f.valueRewriter = rewrite
+	}
+}
+
+// LineNumberTitles adds a title attribute containing the text of each line to
+// its line number, eg. for hover previews. Titles longer than maxLen
+// characters are truncated with an ellipsis. A maxLen <= 0 disables titles.
+func LineNumberTitles(maxLen int) Option {
+	return func(f *Formatter) {
+		f.lineTitleLength = maxLen
+	}
+}
+
+// WithGutter renders a column of per-line annotations, eg. blame information,
+// to the left of the code and any line numbers. gutter is called with the line
+// number of each rendered line and returns its annotation, which is padded to
+// a common width. The column is styled as line numbers.
+func WithGutter(gutter func(line int) string) Option {
+	return func(f *Formatter) {
+		f.gutter = gutter
+	}
+}
+
+// FlushLines calls flush with the line number and complete HTML of each line
+// as soon as it has been formatted, before it is written to the output, eg.
+// to stream lines to a client as server-sent events. Notices of omitted lines
+// and the elements wrapping the code are not included. If flush returns an
+// error, formatting stops and Format returns it.
+func FlushLines(flush func(line int, html string) error) Option {
+	return func(f *Formatter) {
+		f.flushLine = flush
+	}
+}
+
+// ScopedCSS writes a <style> block before the highlighted code containing only
+// the CSS rules for the classes it uses, so that output is self-contained
+// without styling every element inline. Implies WithClasses(true).
+//
+// Browsers ignore the obsolete scoped attribute, so the rules apply to the
+// whole page. Unless a ClassPrefix is set, classes are therefore prefixed with
+// one derived from the CSS, so that blocks highlighted with different styles on
+// the same page do not override each other.
+func ScopedCSS(b bool) Option {
+	return func(f *Formatter) {
+		f.scopedCSS = b
+		if b {
+			f.Classes = true
+		}
+	}
+}
+
+// WithDefaultStyle overrides the Text and Background entries of the style
+// passed to Format and WriteCSS with entry, eg. to change the colour of plain
+// text without editing the style. Attributes not set in entry are inherited
+// from the style, as are the attributes of tokens that do not set their own.
+func WithDefaultStyle(entry chroma.StyleEntry) Option {
+	return func(f *Formatter) {
+		f.defaultStyle = &entry
+	}
+}
+
+// WithLanguageBadge renders a badge containing name, eg. "Go", in a corner of
+// the code block. See LanguageBadgeStyle.
+func WithLanguageBadge(name string) Option {
+	return func(f *Formatter) {
+		f.badge = name
+	}
+}
+
+// LanguageBadgeStyle sets the corner in which the WithLanguageBadge badge is
+// rendered, one of "top-right" (the default), "top-left", "bottom-right" or
+// "bottom-left", and its colours. Unset colours default to the inverse of the
+// style's Background.
+func LanguageBadgeStyle(corner string, colour, background chroma.Colour) Option {
+	return func(f *Formatter) {
+		f.badgeCorner = corner
+		f.badgeColour = colour
+		f.badgeBackground = background
+	}
+}
+
+// WithXHTML produces well-formed XHTML, eg. for XML consumers. Standalone
+// documents include an XML declaration, declare the XHTML namespace and wrap
+// their CSS in a CDATA section.
+func WithXHTML(b bool) Option { return func(f *Formatter) { f.xhtml = b } }
+
 // New HTML formatter.
 func New(options ...Option) *Formatter {
 	f := &Formatter{
 		baseLineNumber: 1,
 		preWrapper:     defaultPreWrapper,
+		cursor:         -1,
 	}
 	for _, option := range options {
 		option(f)
@@ -152,6 +376,14 @@ var (
 			return `</pre>`
 		},
 	}
+	preOnlyPreWrapper = preWrapper{
+		start: func(code bool, styleAttr string) string {
+			return fmt.Sprintf(`<pre tabindex="0"%s>`, styleAttr)
+		},
+		end: func(code bool) string {
+			return `</pre>`
+		},
+	}
 )
 
 // Formatter that generates HTML.
@@ -162,13 +394,62 @@ type Formatter struct {
 	allClasses          bool
 	preWrapper          PreWrapper
 	tabWidth            int
+	alignTabs           bool
 	wrapLongLines       bool
 	lineNumbers         bool
 	lineNumbersInTable  bool
+	lineNumbersRight    bool
 	linkableLineNumbers bool
 	lineNumbersIDPrefix string
 	highlightRanges     highlightRanges
 	baseLineNumber      int
+	maxLines            int
+	cursor              int
+	inlineCode          bool
+	mergeSpans          bool
+	singleElementLines  bool
+	indentGuideWidth    int
+	indentGuideColour   string
+	gutterMarkerColour  string
+	ariaLabels          map[chroma.TokenType]string
+	lang                string
+	contextView         *contextView
+	valueRewriter       func(chroma.Token) string
+	lineTitleLength     int
+	gutter              func(line int) string
+	flushLine           func(line int, html string) error
+	scopedCSS           bool
+	defaultStyle        *chroma.StyleEntry
+	xhtml               bool
+	badge               string
+	badgeCorner         string
+	badgeColour         chroma.Colour
+	badgeBackground     chroma.Colour
+}
+
+type contextView struct {
+	target        [2]int
+	before, after int
+}
+
+// fold returns the lines within the context of the target, and the number of
+// lines omitted before and after them.
+func (c *contextView) fold(lines [][]chroma.Token, baseLineNumber int) (out [][]chroma.Token, before, after int) {
+	start, end := 0, len(lines)
+	if c.before >= 0 && c.target[0]-baseLineNumber-c.before > start {
+		start = c.target[0] - baseLineNumber - c.before
+	}
+	if c.after >= 0 && c.target[1]-baseLineNumber+1+c.after < end {
+		end = c.target[1] - baseLineNumber + 1 + c.after
+	}
+	if start > end {
+		start = end
+	}
+	return lines[start:end], start, len(lines) - end
+}
+
+func (c *contextView) dimmed(line int) bool {
+	return line < c.target[0] || line > c.target[1]
 }
 
 type highlightRanges [][2]int
@@ -178,29 +459,86 @@ func (h highlightRanges) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 func (h highlightRanges) Less(i, j int) bool { return h[i][0] < h[j][0] }
 
 func (f *Formatter) Format(w io.Writer, style *chroma.Style, iterator chroma.Iterator) (err error) {
-	return f.writeHTML(w, style, iterator.Tokens())
+	defer func() {
+		if perr := recover(); perr != nil {
+			err = perr.(error)
+		}
+	}()
+	return f.writeHTML(w, style, f.rewriteValues(iterator.Tokens()))
+}
+
+// rewriteValues applies the WithValueRewriter callback, if any, to tokens.
+func (f *Formatter) rewriteValues(tokens []chroma.Token) []chroma.Token {
+	if f.valueRewriter == nil {
+		return tokens
+	}
+	out := make([]chroma.Token, len(tokens))
+	for i, token := range tokens {
+		out[i] = chroma.Token{Type: token.Type, Value: f.valueRewriter(token)}
+	}
+	return out
+}
+
+// FormatToStrings formats tokens as HTML while also collecting their raw text in
+// the same pass, eg. for copying both rich and plain text to a clipboard.
+func (f *Formatter) FormatToStrings(style *chroma.Style, iterator chroma.Iterator) (html string, text string, err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			err = perr.(error)
+		}
+	}()
+	tokens := f.rewriteValues(iterator.Tokens())
+	buf := &strings.Builder{}
+	if err := f.writeHTML(buf, style, tokens); err != nil {
+		return "", "", err
+	}
+	return buf.String(), chroma.Stringify(tokens...), nil
 }
 
 // We deliberately don't use html/template here because it is two orders of magnitude slower (benchmarked).
 //
 // OTOH we need to be super careful about correct escaping...
 func (f *Formatter) writeHTML(w io.Writer, style *chroma.Style, tokens []chroma.Token) (err error) { // nolint: gocyclo
+	if f.scopedCSS && f.Classes && !f.standalone && f.prefix == "" {
+		scoped := *f
+		scoped.prefix = f.scopedPrefix(style)
+		return scoped.writeHTML(w, style, tokens)
+	}
 	css := f.styleToCSS(style)
 	if !f.Classes {
 		for t, style := range css {
 			css[t] = compressStyle(style)
 		}
 	}
+	if f.scopedCSS && f.Classes && !f.standalone {
+		fmt.Fprint(w, "<style>\n")
+		if err := f.writeCSS(w, style, f.usedTypes(tokens)); err != nil {
+			return err
+		}
+		fmt.Fprint(w, "</style>\n")
+	}
+	if f.inlineCode {
+		return f.writeInlineHTML(w, css, tokens)
+	}
 	if f.standalone {
-		fmt.Fprint(w, "<html>\n")
+		styleStart, styleEnd := "<style type=\"text/css\">\n", "</style>"
+		if f.xhtml {
+			fmt.Fprint(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+			fmt.Fprint(w, "<html xmlns=\"http://www.w3.org/1999/xhtml\">\n<head>")
+			styleStart, styleEnd = "<style type=\"text/css\">/*<![CDATA[*/\n", "/*]]>*/</style></head>\n"
+		} else {
+			fmt.Fprint(w, "<html>\n")
+		}
 		if f.Classes {
-			fmt.Fprint(w, "<style type=\"text/css\">\n")
+			fmt.Fprint(w, styleStart)
 			err = f.WriteCSS(w, style)
 			if err != nil {
 				return err
 			}
 			fmt.Fprintf(w, "body { %s; }\n", css[chroma.Background])
-			fmt.Fprint(w, "</style>")
+			fmt.Fprint(w, styleEnd)
+		} else if f.xhtml {
+			fmt.Fprint(w, "</head>\n")
 		}
 		fmt.Fprintf(w, "<body%s>\n", f.styleAttr(css, chroma.Background))
 	}
@@ -208,50 +546,78 @@ func (f *Formatter) writeHTML(w io.Writer, style *chroma.Style, tokens []chroma.
 	wrapInTable := f.lineNumbers && f.lineNumbersInTable
 
 	lines := chroma.SplitTokensIntoLines(tokens)
-	lineDigits := len(fmt.Sprintf("%d", f.baseLineNumber+len(lines)-1))
-	highlightIndex := 0
+	cursorLine, cursorToken := -1, -1
+	if f.cursor >= 0 {
+		lines, cursorLine, cursorToken = splitCursor(lines, f.cursor)
+	}
+	// Lines omitted before and after those rendered.
+	folded, truncated := 0, 0
+	if f.contextView != nil {
+		lines, folded, truncated = f.contextView.fold(lines, f.baseLineNumber)
+		cursorLine -= folded
+	}
+	firstLine := f.baseLineNumber + folded
+	if f.maxLines > 0 && len(lines) > f.maxLines {
+		truncated += len(lines) - f.maxLines
+		lines = lines[:f.maxLines]
+	}
+	lineDigits := len(fmt.Sprintf("%d", firstLine+len(lines)-1))
+	gutters, gutterWidth := f.gutterText(firstLine, len(lines))
 
 	if wrapInTable {
 		// List line numbers in its own <td>
 		fmt.Fprintf(w, "<div%s>\n", f.styleAttr(css, chroma.PreWrapper))
 		fmt.Fprintf(w, "<table%s><tr>", f.styleAttr(css, chroma.LineTable))
-		fmt.Fprintf(w, "<td%s>\n", f.styleAttr(css, chroma.LineTableTD))
-		fmt.Fprintf(w, f.preWrapper.Start(false, f.styleAttr(css, chroma.PreWrapper)))
-		for index := range lines {
-			line := f.baseLineNumber + index
-			highlight, next := f.shouldHighlight(highlightIndex, line)
-			if next {
-				highlightIndex++
-			}
-			if highlight {
-				fmt.Fprintf(w, "<span%s>", f.styleAttr(css, chroma.LineHighlight))
-			}
-
-			fmt.Fprintf(w, "<span%s%s>%s\n</span>", f.styleAttr(css, chroma.LineNumbersTable), f.lineIDAttribute(line), f.lineTitleWithLinkIfNeeded(lineDigits, line))
-
-			if highlight {
-				fmt.Fprintf(w, "</span>")
-			}
+		if gutters != nil {
+			f.writeGutterTD(w, css, gutters, gutterWidth, folded, truncated)
+			fmt.Fprint(w, "\n")
+		}
+		if !f.lineNumbersRight {
+			f.writeLineNumbersTD(w, css, firstLine, lines, lineDigits, folded, truncated)
+			fmt.Fprint(w, "\n")
 		}
-		fmt.Fprint(w, f.preWrapper.End(false))
-		fmt.Fprint(w, "</td>\n")
 		fmt.Fprintf(w, "<td%s>\n", f.styleAttr(css, chroma.LineTableTD, "width:100%"))
 	}
 
-	fmt.Fprintf(w, f.preWrapper.Start(true, f.styleAttr(css, chroma.PreWrapper)))
+	fmt.Fprintf(w, f.preWrapper.Start(true, f.styleAttr(css, chroma.PreWrapper)+f.langAttr()))
+
+	if f.badge != "" {
+		f.writeBadge(w, style)
+	}
+
+	if folded > 0 {
+		f.writeNotice(w, css, wrapInTable, lineDigits, gutterWidth, foldNotice(folded))
+	}
 
-	highlightIndex = 0
+	out := w
+	lineBuf := &strings.Builder{}
+	highlightIndex := 0
 	for index, tokens := range lines {
 		// 1-based line number.
-		line := f.baseLineNumber + index
+		line := firstLine + index
+		if f.flushLine != nil {
+			lineBuf.Reset()
+			w = lineBuf
+		}
 		highlight, next := f.shouldHighlight(highlightIndex, line)
 		if next {
 			highlightIndex++
 		}
 
+		marker := highlight && f.gutterMarkerColour != ""
+		if marker {
+			highlight = false
+		}
+
 		// Start of Line
 		fmt.Fprint(w, `<span`)
-		if highlight {
+		if marker && !f.lineNumbers {
+			if f.Classes {
+				fmt.Fprintf(w, ` class="%s %sgm">`, f.class(chroma.Line), f.prefix)
+			} else {
+				fmt.Fprintf(w, ` style="%s;%s">`, css[chroma.Line], f.gutterMarkerCSS())
+			}
+		} else if highlight {
 			// Line + LineHighlight
 			if f.Classes {
 				fmt.Fprintf(w, ` class="%s %s"`, f.class(chroma.Line), f.class(chroma.LineHighlight))
@@ -263,31 +629,58 @@ func (f *Formatter) writeHTML(w io.Writer, style *chroma.Style, tokens []chroma.
 			fmt.Fprintf(w, "%s>", f.styleAttr(css, chroma.Line))
 		}
 
+		if gutters != nil && !wrapInTable {
+			f.writeGutter(w, css, gutters[index], gutterWidth, "")
+		}
+
 		// Line number
+		lineNumber := ""
 		if f.lineNumbers && !wrapInTable {
-			fmt.Fprintf(w, "<span%s%s>%s</span>", f.styleAttr(css, chroma.LineNumbers), f.lineIDAttribute(line), f.lineTitleWithLinkIfNeeded(lineDigits, line))
+			lineNumber = fmt.Sprintf("<span%s%s%s>%s</span>", f.styleAttr(css, chroma.LineNumbers), f.lineIDAttribute(line), f.lineTextTitle(tokens), f.lineTitleWithLinkIfNeeded(lineDigits, line))
+			if marker {
+				lineNumber = fmt.Sprintf("<span%s>%s</span>", f.gutterMarkerAttr(), lineNumber)
+			}
+		}
+		if !f.lineNumbersRight {
+			fmt.Fprint(w, lineNumber)
 		}
 
-		fmt.Fprintf(w, `<span%s>`, f.styleAttr(css, chroma.CodeLine))
-
-		for _, token := range tokens {
-			html := html.EscapeString(token.String())
-			attr := f.styleAttr(css, token.Type)
-			if attr != "" {
-				html = fmt.Sprintf("<span%s>%s</span>", attr, html)
-			}
-			fmt.Fprint(w, html)
+		if f.singleElementLines {
+			f.writeSingleElementLine(w, css, style, line, tokens)
+		} else {
+			f.writeCodeLine(w, css, line, tokens, index == cursorLine, cursorToken)
 		}
 
-		fmt.Fprint(w, `</span>`) // End of CodeLine
+		if f.lineNumbersRight {
+			fmt.Fprint(w, lineNumber)
+		}
 
 		fmt.Fprint(w, `</span>`) // End of Line
+
+		if f.flushLine != nil {
+			w = out
+			if err := f.flushLine(line, lineBuf.String()); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, lineBuf.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if truncated > 0 {
+		f.writeNotice(w, css, wrapInTable, lineDigits, gutterWidth, truncationNotice(truncated))
 	}
 
 	fmt.Fprintf(w, f.preWrapper.End(true))
 
 	if wrapInTable {
-		fmt.Fprint(w, "</td></tr></table>\n")
+		fmt.Fprint(w, "</td>")
+		if f.lineNumbersRight {
+			fmt.Fprint(w, "\n")
+			f.writeLineNumbersTD(w, css, firstLine, lines, lineDigits, folded, truncated)
+		}
+		fmt.Fprint(w, "</tr></table>\n")
 		fmt.Fprint(w, "</div>\n")
 	}
 
@@ -299,6 +692,499 @@ func (f *Formatter) writeHTML(w io.Writer, style *chroma.Style, tokens []chroma.
 	return nil
 }
 
+// writeCodeLine writes the code of a line, with a span per token. If
+// hasCursor is true the cursor is rendered around the token at cursorToken.
+func (f *Formatter) writeCodeLine(w io.Writer, css map[chroma.TokenType]string, line int, tokens []chroma.Token, hasCursor bool, cursorToken int) {
+	fmt.Fprintf(w, `<span%s>`, f.codeLineAttr(css, line))
+
+	indent := ""
+	if f.indentGuideWidth > 0 {
+		var rest []chroma.Token
+		var removed int
+		indent, rest, removed = splitIndent(tokens)
+		if hasCursor && cursorToken < removed {
+			indent = ""
+		} else {
+			tokens = rest
+			if hasCursor {
+				cursorToken -= removed
+			}
+		}
+	}
+	fmt.Fprint(w, f.indentGuides(indent))
+	col := f.tabColumn(0, indent)
+	if f.mergeSpans {
+		tokens = f.mergeRuns(css, tokens, hasCursor, &cursorToken)
+	}
+	for i, token := range tokens {
+		cursor := hasCursor && i == cursorToken
+		if cursor {
+			fmt.Fprintf(w, "<span%s>", f.styleAttr(css, chroma.Cursor))
+		}
+		if f.alignTabs {
+			col = f.writeTabAlignedToken(w, css, token, col)
+		} else {
+			f.writeToken(w, css, token)
+		}
+		if cursor {
+			fmt.Fprint(w, "</span>")
+		}
+	}
+
+	fmt.Fprint(w, `</span>`) // End of CodeLine
+}
+
+// writeSingleElementLine writes the code of a line as text within a single
+// element, coloured by a gradient with a hard-edged run per colour.
+func (f *Formatter) writeSingleElementLine(w io.Writer, css map[chroma.TokenType]string, style *chroma.Style, line int, tokens []chroma.Token) {
+	attr := f.codeLineAttr(css, line)
+	if runs := f.colourRunsCSS(style, tokens); runs != "" {
+		if strings.HasPrefix(attr, ` style="`) {
+			attr = strings.TrimSuffix(attr, `"`) + ";" + runs + `"`
+		} else {
+			attr += ` style="` + runs + `"`
+		}
+	}
+	fmt.Fprintf(w, `<span%s>`, attr)
+	for _, token := range tokens {
+		writeEscaped(w, token.Value)
+	}
+	fmt.Fprint(w, `</span>`) // End of CodeLine
+}
+
+// colourRunsCSS returns CSS colouring text with a run per colour in tokens,
+// each spanning the columns of its tokens, or "" if the tokens occupy no
+// columns.
+func (f *Formatter) colourRunsCSS(style *chroma.Style, tokens []chroma.Token) string {
+	type run struct {
+		colour     string
+		start, end int
+	}
+	var runs []run
+	col := 0
+	for _, token := range tokens {
+		end := f.tabColumn(col, token.Value)
+		if end == col {
+			continue
+		}
+		colour := "#000000"
+		if c := style.Get(token.Type).Colour; c.IsSet() {
+			colour = c.String()
+		}
+		if n := len(runs); n > 0 && runs[n-1].colour == colour {
+			runs[n-1].end = end
+		} else {
+			runs = append(runs, run{colour, col, end})
+		}
+		col = end
+	}
+	if len(runs) == 0 {
+		return ""
+	}
+	stops := make([]string, len(runs))
+	for i, r := range runs {
+		stops[i] = fmt.Sprintf("%s %dch %dch", r.colour, r.start, r.end)
+	}
+	return "background:linear-gradient(to right," + strings.Join(stops, ",") + ");" +
+		"-webkit-background-clip:text;background-clip:text;color:transparent"
+}
+
+// scopedPrefix returns a class prefix derived from the CSS for style, so that
+// the rules for different styles do not collide.
+func (f *Formatter) scopedPrefix(style *chroma.Style) string {
+	h := fnv.New32a()
+	_ = f.writeCSS(h, style, nil)
+	return fmt.Sprintf("c%08x-", h.Sum32())
+}
+
+// splitIndent splits leading whitespace off a line of tokens, returning it
+// and the remaining tokens, along with the number of tokens wholly consumed.
+func splitIndent(tokens []chroma.Token) (indent string, rest []chroma.Token, removed int) {
+	for removed < len(tokens) {
+		token := tokens[removed]
+		trimmed := strings.TrimLeft(token.Value, " \t")
+		indent += token.Value[:len(token.Value)-len(trimmed)]
+		if trimmed != "" {
+			token.Value = trimmed
+			rest = append([]chroma.Token{token}, tokens[removed+1:]...)
+			return indent, rest, removed
+		}
+		removed++
+	}
+	return indent, nil, removed
+}
+
+// indentGuides renders leading whitespace with a guide span for each indent level.
+func (f *Formatter) indentGuides(indent string) string {
+	if indent == "" {
+		return ""
+	}
+	tabWidth := f.tabStop()
+	attr := fmt.Sprintf(` class="%sig"`, f.prefix)
+	if !f.Classes {
+		attr = fmt.Sprintf(` style="%s"`, f.indentGuideCSS())
+	}
+	out := &strings.Builder{}
+	col, level, start := 0, 0, 0
+	for i, c := range indent {
+		if c == '\t' {
+			col = (col/tabWidth + 1) * tabWidth
+		} else {
+			col++
+		}
+		if col/f.indentGuideWidth > level {
+			level = col / f.indentGuideWidth
+			fmt.Fprintf(out, "<span%s>%s</span>", attr, indent[start:i+1])
+			start = i + 1
+		}
+	}
+	out.WriteString(indent[start:])
+	return out.String()
+}
+
+// tabStop returns the number of columns between tab stops.
+func (f *Formatter) tabStop() int {
+	if f.tabWidth <= 0 {
+		return 8
+	}
+	return f.tabWidth
+}
+
+// tabColumn returns the column reached by writing s from column col.
+func (f *Formatter) tabColumn(col int, s string) int {
+	for _, r := range s {
+		if r == '\t' {
+			col += f.tabStop() - col%f.tabStop()
+		} else {
+			col += chroma.RuneWidth(r)
+		}
+	}
+	return col
+}
+
+// writeTabAlignedToken is like writeToken, but wraps each tab in a span
+// extending from column col to the next tab stop. It returns the column
+// reached after the token.
+func (f *Formatter) writeTabAlignedToken(w io.Writer, css map[chroma.TokenType]string, token chroma.Token, col int) int {
+	attr := f.tokenAttr(css, token.Type)
+	if attr != "" {
+		fmt.Fprintf(w, "<span%s>", attr)
+	}
+	start := 0
+	for i, r := range token.Value {
+		if r != '\t' {
+			col += chroma.RuneWidth(r)
+			continue
+		}
+		writeEscaped(w, token.Value[start:i])
+		width := f.tabStop() - col%f.tabStop()
+		fmt.Fprintf(w, "<span%s>\t</span>", f.tabStopAttr(width))
+		col += width
+		start = i + 1
+	}
+	writeEscaped(w, token.Value[start:])
+	if attr != "" {
+		io.WriteString(w, "</span>")
+	}
+	return col
+}
+
+func (f *Formatter) tabStopAttr(width int) string {
+	if f.Classes {
+		return fmt.Sprintf(` class="%sts%d"`, f.prefix, width)
+	}
+	return fmt.Sprintf(` style="%s"`, tabStopCSS(width))
+}
+
+func tabStopCSS(width int) string {
+	return fmt.Sprintf("display:inline-block;width:%dch;overflow:hidden;vertical-align:top", width)
+}
+
+// writeBadge writes the language badge.
+func (f *Formatter) writeBadge(w io.Writer, style *chroma.Style) {
+	attr := fmt.Sprintf(` class="%sbadge"`, f.prefix)
+	if !f.Classes {
+		attr = fmt.Sprintf(` style="%s"`, compressStyle(f.badgeCSS(style)))
+	}
+	fmt.Fprintf(w, "<span%s>", attr)
+	writeEscaped(w, f.badge)
+	fmt.Fprint(w, "</span>")
+}
+
+func (f *Formatter) badgeCSS(style *chroma.Style) string {
+	bg := style.Get(chroma.Background)
+	colour, background := f.badgeColour, f.badgeBackground
+	if !colour.IsSet() {
+		colour = bg.Background
+		if !colour.IsSet() {
+			colour = chroma.MustParseColour("#ffffff")
+		}
+	}
+	if !background.IsSet() {
+		background = bg.Colour
+		if !background.IsSet() {
+			background = chroma.MustParseColour("#000000")
+		}
+	}
+	vertical, horizontal := "top", "right"
+	switch f.badgeCorner {
+	case "top-left":
+		horizontal = "left"
+	case "bottom-right":
+		vertical = "bottom"
+	case "bottom-left":
+		vertical, horizontal = "bottom", "left"
+	}
+	return fmt.Sprintf("position: absolute; %s: 0; %s: 0; padding: 0 0.4em; font-size: 0.8em; user-select: none; color: %s; background-color: %s",
+		vertical, horizontal, colour, background)
+}
+
+func (f *Formatter) gutterMarkerAttr() string {
+	if f.Classes {
+		return fmt.Sprintf(` class="%sgm"`, f.prefix)
+	}
+	return fmt.Sprintf(` style="%s"`, f.gutterMarkerCSS())
+}
+
+func (f *Formatter) gutterMarkerCSS() string {
+	return fmt.Sprintf("box-shadow: inset 3px 0 0 0 %s", f.gutterMarkerColour)
+}
+
+// codeLineAttr returns the attributes for the CodeLine span of line, dimming
+// it if it is context outside of the ContextView target.
+func (f *Formatter) codeLineAttr(css map[chroma.TokenType]string, line int) string {
+	if f.contextView == nil || !f.contextView.dimmed(line) {
+		return f.styleAttr(css, chroma.CodeLine)
+	}
+	if f.Classes {
+		return fmt.Sprintf(` class="%s %sdim"`, f.class(chroma.CodeLine), f.prefix)
+	}
+	return fmt.Sprintf(` style="%s"`, strings.TrimPrefix(css[chroma.CodeLine]+";"+contextDimCSS, ";"))
+}
+
+const contextDimCSS = "opacity:0.5"
+
+func (f *Formatter) indentGuideCSS() string {
+	return fmt.Sprintf("box-shadow: 1px 0 0 0 %s inset", f.indentGuideColour)
+}
+
+// mergeRuns merges adjacent tokens that render with identical styles. If
+// hasCursor is true, the token at *cursor is never merged and *cursor is
+// updated to its new index.
+func (f *Formatter) mergeRuns(css map[chroma.TokenType]string, tokens []chroma.Token, hasCursor bool, cursor *int) []chroma.Token {
+	out := make([]chroma.Token, 0, len(tokens))
+	lastAttr := ""
+	mergeable := false
+	for i, token := range tokens {
+		attr := f.tokenAttr(css, token.Type)
+		isCursor := hasCursor && i == *cursor
+		if mergeable && !isCursor && attr == lastAttr {
+			out[len(out)-1].Value += token.Value
+			continue
+		}
+		if isCursor {
+			*cursor = len(out)
+		}
+		out = append(out, token)
+		lastAttr = attr
+		mergeable = !isCursor
+	}
+	return out
+}
+
+// writeInlineHTML writes tokens as a single line of spans wrapped in <code>.
+func (f *Formatter) writeInlineHTML(w io.Writer, css map[chroma.TokenType]string, tokens []chroma.Token) error {
+	for len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		last.Value = strings.TrimRight(last.Value, "\r\n")
+		if last.Value != "" {
+			tokens = append(tokens[:len(tokens)-1:len(tokens)-1], last)
+			break
+		}
+		tokens = tokens[:len(tokens)-1]
+	}
+	for _, token := range tokens {
+		if strings.ContainsAny(token.Value, "\r\n") {
+			return fmt.Errorf("inline code must not contain newlines")
+		}
+	}
+	fmt.Fprintf(w, "<code%s%s>", f.styleAttr(css, chroma.PreWrapper), f.langAttr())
+	for _, token := range tokens {
+		f.writeToken(w, css, token)
+	}
+	fmt.Fprint(w, "</code>")
+	return nil
+}
+
+// writeNotice writes a line containing an unnumbered notice, eg. of omitted lines.
+func (f *Formatter) writeNotice(w io.Writer, css map[chroma.TokenType]string, wrapInTable bool, lineDigits, gutterWidth int, notice string) {
+	fmt.Fprintf(w, "<span%s>", f.styleAttr(css, chroma.Line))
+	if f.gutter != nil && !wrapInTable {
+		f.writeGutter(w, css, "", gutterWidth, "")
+	}
+	lineNumber := ""
+	if f.lineNumbers && !wrapInTable {
+		lineNumber = fmt.Sprintf("<span%s>%*s</span>", f.styleAttr(css, chroma.LineNumbers), lineDigits, "")
+	}
+	if !f.lineNumbersRight {
+		fmt.Fprint(w, lineNumber)
+	}
+	fmt.Fprintf(w, "<span%s><span%s>%s</span></span>", f.styleAttr(css, chroma.CodeLine), f.styleAttr(css, chroma.Comment), notice)
+	if f.lineNumbersRight {
+		fmt.Fprint(w, lineNumber)
+	}
+	fmt.Fprint(w, `</span>`)
+}
+
+// writeLineNumbersTD writes the <td> containing line numbers when they are
+// rendered in a table.
+func (f *Formatter) writeLineNumbersTD(w io.Writer, css map[chroma.TokenType]string, firstLine int, lines [][]chroma.Token, lineDigits, folded, truncated int) {
+	fmt.Fprintf(w, "<td%s>\n", f.styleAttr(css, chroma.LineTableTD))
+	fmt.Fprintf(w, f.preWrapper.Start(false, f.styleAttr(css, chroma.PreWrapper)))
+	if folded > 0 {
+		// Keep the line number column aligned with the fold notice.
+		fmt.Fprintf(w, "<span%s>%*s\n</span>", f.styleAttr(css, chroma.LineNumbersTable), lineDigits, "")
+	}
+	highlightIndex := 0
+	for index := range lines {
+		line := firstLine + index
+		highlight, next := f.shouldHighlight(highlightIndex, line)
+		if next {
+			highlightIndex++
+		}
+		if highlight {
+			if f.gutterMarkerColour != "" {
+				fmt.Fprintf(w, "<span%s>", f.gutterMarkerAttr())
+			} else {
+				fmt.Fprintf(w, "<span%s>", f.styleAttr(css, chroma.LineHighlight))
+			}
+		}
+
+		fmt.Fprintf(w, "<span%s%s%s>%s\n</span>", f.styleAttr(css, chroma.LineNumbersTable), f.lineIDAttribute(line), f.lineTextTitle(lines[index]), f.lineTitleWithLinkIfNeeded(lineDigits, line))
+
+		if highlight {
+			fmt.Fprintf(w, "</span>")
+		}
+	}
+	if truncated > 0 {
+		// Keep the line number column aligned with the truncation notice.
+		fmt.Fprintf(w, "<span%s>%*s\n</span>", f.styleAttr(css, chroma.LineNumbersTable), lineDigits, "")
+	}
+	fmt.Fprint(w, f.preWrapper.End(false))
+	fmt.Fprint(w, "</td>")
+}
+
+// gutterText returns the gutter annotation of each of count lines starting at
+// firstLine, and the width of the widest, or nil if there is no gutter.
+func (f *Formatter) gutterText(firstLine, count int) (gutters []string, width int) {
+	if f.gutter == nil {
+		return nil, 0
+	}
+	gutters = make([]string, count)
+	for i := range gutters {
+		gutters[i] = f.gutter(firstLine + i)
+		if n := utf8.RuneCountInString(gutters[i]); n > width {
+			width = n
+		}
+	}
+	return gutters, width
+}
+
+// writeGutter writes the gutter annotation of a line, padded to width and
+// followed by eol.
+func (f *Formatter) writeGutter(w io.Writer, css map[chroma.TokenType]string, text string, width int, eol string) {
+	fmt.Fprintf(w, "<span%s>", f.gutterAttr(css))
+	writeEscaped(w, text)
+	fmt.Fprintf(w, "%s%s</span>", strings.Repeat(" ", width-utf8.RuneCountInString(text)), eol)
+}
+
+func (f *Formatter) gutterAttr(css map[chroma.TokenType]string) string {
+	if f.Classes {
+		return fmt.Sprintf(` class="%s %sgt"`, f.class(chroma.LineNumbers), f.prefix)
+	}
+	return f.styleAttr(css, chroma.LineNumbers)
+}
+
+// writeGutterTD writes the <td> containing gutter annotations when line
+// numbers are rendered in a table.
+func (f *Formatter) writeGutterTD(w io.Writer, css map[chroma.TokenType]string, gutters []string, width, folded, truncated int) {
+	fmt.Fprintf(w, "<td%s>\n", f.styleAttr(css, chroma.LineTableTD))
+	fmt.Fprintf(w, f.preWrapper.Start(false, f.styleAttr(css, chroma.PreWrapper)))
+	if folded > 0 {
+		f.writeGutter(w, css, "", width, "\n")
+	}
+	for _, text := range gutters {
+		f.writeGutter(w, css, text, width, "\n")
+	}
+	if truncated > 0 {
+		f.writeGutter(w, css, "", width, "\n")
+	}
+	fmt.Fprint(w, f.preWrapper.End(false))
+	fmt.Fprint(w, "</td>")
+}
+
+// splitCursor splits the token containing the byte offset so that the cursor
+// covers exactly one character, returning the line and token index of the
+// cursor. A cursor on a newline, or past the end of the text, is rendered as a
+// space.
+func splitCursor(lines [][]chroma.Token, offset int) (out [][]chroma.Token, line int, index int) {
+	pos := 0
+	for li, tokens := range lines {
+		for ti, token := range tokens {
+			if offset >= pos+len(token.Value) {
+				pos += len(token.Value)
+				continue
+			}
+			rel := offset - pos
+			_, size := utf8.DecodeRuneInString(token.Value[rel:])
+			cursor := token.Clone()
+			cursor.Value = token.Value[rel : rel+size]
+			if cursor.Value == "\n" {
+				cursor.Value = " "
+				size = 0
+			}
+			replacement := []chroma.Token{}
+			if rel > 0 {
+				head := token.Clone()
+				head.Value = token.Value[:rel]
+				replacement = append(replacement, head)
+			}
+			index = len(replacement) + ti
+			replacement = append(replacement, cursor)
+			if rel+size < len(token.Value) {
+				tail := token.Clone()
+				tail.Value = token.Value[rel+size:]
+				replacement = append(replacement, tail)
+			}
+			lines[li] = append(tokens[:ti:ti], append(replacement, tokens[ti+1:]...)...)
+			return lines, li, index
+		}
+	}
+	cursor := chroma.Token{Type: chroma.Text, Value: " "}
+	if n := len(lines); n > 0 {
+		last := lines[n-1]
+		if len(last) > 0 && !strings.HasSuffix(last[len(last)-1].Value, "\n") {
+			lines[n-1] = append(last, cursor)
+			return lines, n - 1, len(last)
+		}
+	}
+	return append(lines, []chroma.Token{cursor}), len(lines), 0
+}
+
+func foldNotice(n int) string {
+	if n == 1 {
+		return "… (1 earlier line)"
+	}
+	return fmt.Sprintf("… (%d earlier lines)", n)
+}
+
+func truncationNotice(n int) string {
+	if n == 1 {
+		return "… (1 more line)"
+	}
+	return fmt.Sprintf("… (%d more lines)", n)
+}
+
 func (f *Formatter) lineIDAttribute(line int) string {
 	if !f.linkableLineNumbers {
 		return ""
@@ -306,6 +1192,19 @@ func (f *Formatter) lineIDAttribute(line int) string {
 	return fmt.Sprintf(" id=\"%s\"", f.lineID(line))
 }
 
+// lineTextTitle returns a title attribute containing the text of a line, if
+// enabled with LineNumberTitles.
+func (f *Formatter) lineTextTitle(tokens []chroma.Token) string {
+	if f.lineTitleLength <= 0 {
+		return ""
+	}
+	text := strings.TrimRight(chroma.Stringify(tokens...), "\r\n")
+	if utf8.RuneCountInString(text) > f.lineTitleLength {
+		text = string([]rune(text)[:f.lineTitleLength-1]) + "…"
+	}
+	return fmt.Sprintf(` title="%s"`, html.EscapeString(text))
+}
+
 func (f *Formatter) lineTitleWithLinkIfNeeded(lineDigits, line int) string {
 	title := fmt.Sprintf("%*d", lineDigits, line)
 	if !f.linkableLineNumbers {
@@ -351,6 +1250,13 @@ func (f *Formatter) class(t chroma.TokenType) string {
 
 func (f *Formatter) styleAttr(styles map[chroma.TokenType]string, tt chroma.TokenType, extraCSS ...string) string {
 	if f.Classes {
+		// Types defined by the style but with no class of their own, eg. those
+		// synthesised by formatters.Composite, are styled inline.
+		if _, ok := chroma.StandardTypes[tt]; !ok {
+			if css, ok := styles[tt]; ok {
+				return fmt.Sprintf(` style="%s"`, css)
+			}
+		}
 		cls := f.class(tt)
 		if cls == "" {
 			return ""
@@ -371,6 +1277,80 @@ func (f *Formatter) styleAttr(styles map[chroma.TokenType]string, tt chroma.Toke
 	return fmt.Sprintf(` style="%s"`, strings.Join(css, ";"))
 }
 
+// writeToken writes the escaped value of token, wrapped in a span if it is styled.
+func (f *Formatter) writeToken(w io.Writer, css map[chroma.TokenType]string, token chroma.Token) {
+	attr := f.tokenAttr(css, token.Type)
+	if attr != "" {
+		fmt.Fprintf(w, "<span%s>", attr)
+	}
+	writeEscaped(w, token.Value)
+	if attr != "" {
+		io.WriteString(w, "</span>")
+	}
+}
+
+// writeEscaped writes s to w escaped exactly as html.EscapeString would, but
+// writes runs without special characters verbatim rather than building an
+// intermediate string. The special characters are all ASCII, so runs are found
+// bytewise without decoding multi-byte runes.
+func writeEscaped(w io.Writer, s string) {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		if esc := htmlEscapes[s[i]]; esc != "" {
+			io.WriteString(w, s[last:i])
+			io.WriteString(w, esc)
+			last = i + 1
+		}
+	}
+	io.WriteString(w, s[last:])
+}
+
+var htmlEscapes = [256]string{
+	'&':  "&amp;",
+	'\'': "&#39;",
+	'<':  "&lt;",
+	'>':  "&gt;",
+	'"':  "&#34;",
+}
+
+// langAttr returns the data-lang attribute for the wrapping element, if any.
+func (f *Formatter) langAttr() string {
+	if f.lang == "" {
+		return ""
+	}
+	return fmt.Sprintf(` data-lang="%s"`, html.EscapeString(f.lang))
+}
+
+// tokenAttr returns the attributes for a token's span, including its
+// aria-label if enabled.
+func (f *Formatter) tokenAttr(styles map[chroma.TokenType]string, tt chroma.TokenType) string {
+	attr := f.styleAttr(styles, tt)
+	if len(f.ariaLabels) == 0 {
+		return attr
+	}
+	for _, t := range []chroma.TokenType{tt, tt.SubCategory(), tt.Category()} {
+		if label, ok := f.ariaLabels[t]; ok {
+			return fmt.Sprintf(`%s aria-label="%s"`, attr, label)
+		}
+	}
+	return attr
+}
+
+// ariaLabel converts a TokenType name such as "LiteralString" to "literal string".
+func ariaLabel(tt chroma.TokenType) string {
+	out := []rune{}
+	for i, r := range tt.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				out = append(out, ' ')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
 func (f *Formatter) tabWidthStyle() string {
 	if f.tabWidth != 0 && f.tabWidth != 8 {
 		return fmt.Sprintf("; -moz-tab-size: %[1]d; -o-tab-size: %[1]d; tab-size: %[1]d", f.tabWidth)
@@ -380,10 +1360,18 @@ func (f *Formatter) tabWidthStyle() string {
 
 // WriteCSS writes CSS style definitions (without any surrounding HTML).
 func (f *Formatter) WriteCSS(w io.Writer, style *chroma.Style) error {
+	return f.writeCSS(w, style, nil)
+}
+
+// writeCSS writes CSS style definitions, restricted to the token types in used
+// if it is not nil.
+func (f *Formatter) writeCSS(w io.Writer, style *chroma.Style, used map[chroma.TokenType]bool) error {
 	css := f.styleToCSS(style)
 	// Special-case background as it is mapped to the outer ".chroma" class.
-	if _, err := fmt.Fprintf(w, "/* %s */ .%sbg { %s }\n", chroma.Background, f.prefix, css[chroma.Background]); err != nil {
-		return err
+	if used == nil || used[chroma.Background] {
+		if _, err := fmt.Fprintf(w, "/* %s */ .%sbg { %s }\n", chroma.Background, f.prefix, css[chroma.Background]); err != nil {
+			return err
+		}
 	}
 	// Special-case PreWrapper as it is the ".chroma" class.
 	if _, err := fmt.Fprintf(w, "/* %s */ .%schroma { %s }\n", chroma.PreWrapper, f.prefix, css[chroma.PreWrapper]); err != nil {
@@ -391,8 +1379,15 @@ func (f *Formatter) WriteCSS(w io.Writer, style *chroma.Style) error {
 	}
 	// Special-case code column of table to expand width.
 	if f.lineNumbers && f.lineNumbersInTable {
-		if _, err := fmt.Fprintf(w, "/* %s */ .%schroma .%s:last-child { width: 100%%; }",
-			chroma.LineTableTD, f.prefix, f.class(chroma.LineTableTD)); err != nil {
+		codeColumn := "last-child"
+		if f.lineNumbersRight {
+			codeColumn = "first-child"
+			if f.gutter != nil {
+				codeColumn = "nth-child(2)"
+			}
+		}
+		if _, err := fmt.Fprintf(w, "/* %s */ .%schroma .%s:%s { width: 100%%; }",
+			chroma.LineTableTD, f.prefix, f.class(chroma.LineTableTD), codeColumn); err != nil {
 			return err
 		}
 	}
@@ -403,6 +1398,33 @@ func (f *Formatter) WriteCSS(w io.Writer, style *chroma.Style) error {
 			fmt.Fprintf(w, "/* %s targeted by URL anchor */ .%schroma .%s:target { %s }\n", tt, f.prefix, f.class(tt), targetedLineCSS)
 		}
 	}
+	if f.gutterMarkerColour != "" {
+		if _, err := fmt.Fprintf(w, "/* GutterMarker */ .%schroma .%sgm { %s }\n", f.prefix, f.prefix, f.gutterMarkerCSS()); err != nil {
+			return err
+		}
+	}
+	if f.contextView != nil {
+		if _, err := fmt.Fprintf(w, "/* ContextDim */ .%schroma .%sdim { %s }\n", f.prefix, f.prefix, contextDimCSS); err != nil {
+			return err
+		}
+	}
+	if f.alignTabs {
+		for width := 1; width <= f.tabStop(); width++ {
+			if _, err := fmt.Fprintf(w, "/* TabStop */ .%schroma .%sts%d { %s }\n", f.prefix, f.prefix, width, tabStopCSS(width)); err != nil {
+				return err
+			}
+		}
+	}
+	if f.badge != "" {
+		if _, err := fmt.Fprintf(w, "/* LanguageBadge */ .%schroma .%sbadge { %s }\n", f.prefix, f.prefix, f.badgeCSS(style)); err != nil {
+			return err
+		}
+	}
+	if f.indentGuideWidth > 0 {
+		if _, err := fmt.Fprintf(w, "/* IndentGuide */ .%schroma .%sig { %s }\n", f.prefix, f.prefix, f.indentGuideCSS()); err != nil {
+			return err
+		}
+	}
 	tts := []int{}
 	for tt := range css {
 		tts = append(tts, int(tt))
@@ -414,6 +1436,13 @@ func (f *Formatter) WriteCSS(w io.Writer, style *chroma.Style) error {
 		case chroma.Background, chroma.PreWrapper:
 			continue
 		}
+		if used != nil && !used[tt] {
+			continue
+		}
+		// Non-standard types are styled inline.
+		if _, ok := chroma.StandardTypes[tt]; !ok {
+			continue
+		}
 		class := f.class(tt)
 		if class == "" {
 			continue
@@ -426,7 +1455,64 @@ func (f *Formatter) WriteCSS(w io.Writer, style *chroma.Style) error {
 	return nil
 }
 
+// usedTypes returns the token types whose CSS rules are referenced by the HTML
+// for tokens when formatting with classes.
+func (f *Formatter) usedTypes(tokens []chroma.Token) map[chroma.TokenType]bool {
+	used := map[chroma.TokenType]bool{chroma.PreWrapper: true}
+	if !f.inlineCode {
+		used[chroma.Line] = true
+		used[chroma.CodeLine] = true
+	}
+	if f.lineNumbers || f.gutter != nil {
+		used[chroma.LineNumbers] = true
+	}
+	if f.lineNumbers && f.lineNumbersInTable {
+		used[chroma.LineNumbersTable] = true
+		used[chroma.LineTable] = true
+		used[chroma.LineTableTD] = true
+	}
+	if len(f.highlightRanges) > 0 {
+		used[chroma.LineHighlight] = true
+	}
+	if f.cursor >= 0 {
+		used[chroma.Cursor] = true
+	}
+	if f.contextView != nil || f.maxLines > 0 {
+		// Notices of omitted lines.
+		used[chroma.Comment] = true
+	}
+	for _, token := range tokens {
+		// Tokens are styled by the class of their nearest standard type.
+		tt := token.Type
+		for tt != 0 {
+			if _, ok := chroma.StandardTypes[tt]; ok {
+				break
+			}
+			tt = tt.Parent()
+		}
+		used[tt] = true
+	}
+	return used
+}
+
+// applyDefaultStyle returns style with its Text and Background entries
+// overridden by WithDefaultStyle.
+func (f *Formatter) applyDefaultStyle(style *chroma.Style) *chroma.Style {
+	if f.defaultStyle == nil {
+		return style
+	}
+	overridden, err := style.Builder().
+		AddEntry(chroma.Background, f.defaultStyle.Inherit(style.Get(chroma.Background))).
+		AddEntry(chroma.Text, f.defaultStyle.Inherit(style.Get(chroma.Text))).
+		Build()
+	if err != nil {
+		return style
+	}
+	return overridden
+}
+
 func (f *Formatter) styleToCSS(style *chroma.Style) map[chroma.TokenType]string {
+	style = f.applyDefaultStyle(style)
 	classes := map[chroma.TokenType]string{}
 	bg := style.Get(chroma.Background)
 	// Convert the style.
@@ -440,8 +1526,18 @@ func (f *Formatter) styleToCSS(style *chroma.Style) map[chroma.TokenType]string
 		}
 		classes[t] = StyleEntryToCSS(entry)
 	}
+	// Convert any non-standard types defined by the style, eg. those synthesised by formatters.Composite.
+	for _, t := range style.Types() {
+		if _, ok := chroma.StandardTypes[t]; !ok {
+			classes[t] = StyleEntryToCSS(style.Get(t).Sub(bg))
+		}
+	}
 	classes[chroma.Background] += f.tabWidthStyle()
 	classes[chroma.PreWrapper] += classes[chroma.Background] + `;`
+	// Position the language badge relative to PreWrapper.
+	if f.badge != "" {
+		classes[chroma.PreWrapper] += `position: relative;`
+	}
 	// Make PreWrapper a grid to show highlight style with full width.
 	if len(f.highlightRanges) > 0 {
 		classes[chroma.PreWrapper] += `display: grid;`
@@ -451,6 +1547,11 @@ func (f *Formatter) styleToCSS(style *chroma.Style) map[chroma.TokenType]string
 		classes[chroma.PreWrapper] += `white-space: pre-wrap; word-break: break-word;`
 	}
 	lineNumbersStyle := `white-space: pre; user-select: none; margin-right: 0.4em; padding: 0 0.4em 0 0.4em;`
+	if f.lineNumbersRight {
+		lineNumbersStyle = `white-space: pre; user-select: none; margin-left: 0.4em; padding: 0 0.4em 0 0.4em;`
+		// Push the line numbers to the right hand edge.
+		classes[chroma.CodeLine] = `flex-grow: 1;` + classes[chroma.CodeLine]
+	}
 	// All rules begin with default rules followed by user provided rules
 	classes[chroma.Line] = `display: flex;` + classes[chroma.Line]
 	classes[chroma.LineNumbers] = lineNumbersStyle + classes[chroma.LineNumbers]
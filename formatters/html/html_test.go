@@ -2,8 +2,13 @@ package html
 
 import (
 	"bytes"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"io/ioutil"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -32,6 +37,46 @@ func BenchmarkHTMLFormatter(b *testing.B) {
 	}
 }
 
+var unicodeHeavySource = strings.Repeat("// 𝔘𝔫𝔦𝔠𝔬𝔡𝔢 «ταυτότητα» 😀😃😄 <b>&amp;</b>\nfmt.Println(\"日本語のテキスト 🚀\")\n", 200)
+
+func BenchmarkHTMLFormatterUnicode(b *testing.B) {
+	formatter := New()
+	tokens, err := chroma.Tokenise(lexers.Get("go"), nil, unicodeHeavySource)
+	assert.NoError(b, err)
+	b.SetBytes(int64(len(unicodeHeavySource)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = formatter.Format(ioutil.Discard, styles.Fallback, chroma.Literator(tokens...))
+		assert.NoError(b, err)
+	}
+}
+
+func BenchmarkEscape(b *testing.B) {
+	buf := &bytes.Buffer{}
+	b.Run("EscapeString", func(b *testing.B) {
+		b.SetBytes(int64(len(unicodeHeavySource)))
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			fmt.Fprint(buf, html.EscapeString(unicodeHeavySource))
+		}
+	})
+	b.Run("writeEscaped", func(b *testing.B) {
+		b.SetBytes(int64(len(unicodeHeavySource)))
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			writeEscaped(buf, unicodeHeavySource)
+		}
+	})
+}
+
+func TestWriteEscaped(t *testing.T) {
+	for _, s := range []string{"", "plain", `<a href="x">'&'</a>`, "𝔘𝔫𝔦<𝔠𝔬>𝔡𝔢", unicodeHeavySource, "\xff<\xfe"} {
+		buf := &bytes.Buffer{}
+		writeEscaped(buf, s)
+		assert.Equal(t, html.EscapeString(s), buf.String())
+	}
+}
+
 func TestSplitTokensIntoLines(t *testing.T) {
 	in := []chroma.Token{
 		{Value: "hello", Type: chroma.NameKeyword},
@@ -309,3 +354,546 @@ func TestWriteCssWithAllClasses(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotContains(t, buf.String(), ".chroma . {", "Generated css doesn't contain invalid css")
 }
+
+func TestMaxLines(t *testing.T) {
+	f := New(WithClasses(true), MaxLines(2))
+	it, err := lexers.Get("go").Tokenise(nil, "package main\nfunc main()\n{\nprintln(\"hello world\")\n}\n")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = f.Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="kn">package</span> <span class="nx">main</span>
+</span></span><span class="line"><span class="cl"><span class="kd">func</span> <span class="nf">main</span><span class="p">(</span><span class="p">)</span>
+</span></span><span class="line"><span class="cl"><span class="c">… (3 more lines)</span></span></span></code></pre>`, buf.String())
+}
+
+func TestWithCursor(t *testing.T) {
+	format := func(offset int) string {
+		f := New(WithClasses(true), PreventSurroundingPre(true), WithCursor(offset))
+		it, err := lexers.Get("bash").Tokenise(nil, "echo FOO\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = f.Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="nb">ec</span><span class="cur"><span class="nb">h</span></span><span class="nb">o</span> FOO
+</span></span>`, format(2))
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="nb">echo</span> FOO<span class="cur"> </span>
+</span></span>`, format(8))
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="nb">echo</span> FOO
+</span></span><span class="line"><span class="cl"><span class="cur"> </span></span></span>`, format(9))
+}
+
+func TestFormatToStrings(t *testing.T) {
+	source := "package main\nfunc main()\n{\nprintln(\"hello <world>\")\n}\n"
+	it, err := lexers.Get("go").Tokenise(nil, source)
+	assert.NoError(t, err)
+
+	html, text, err := New(WithClasses(true)).FormatToStrings(styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Equal(t, source, text)
+	assert.Contains(t, html, `<span class="s">&#34;hello &lt;world&gt;&#34;</span>`)
+}
+
+func TestWithPreCode(t *testing.T) {
+	format := func(f *Formatter) string {
+		it, err := lexers.Get("bash").Tokenise(nil, "echo FOO")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = f.Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nb">echo</span> FOO</span></span></code></pre>`,
+		format(New(WithClasses(true), WithPreCode(true))))
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><span class="line"><span class="cl"><span class="nb">echo</span> FOO</span></span></pre>`,
+		format(New(WithClasses(true), WithPreCode(false))))
+	assert.Equal(t, `<div class="chroma">
+<table class="lntable"><tr><td class="lntd">
+<pre tabindex="0" class="chroma"><span class="lnt">1
+</span></pre></td>
+<td class="lntd">
+<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nb">echo</span> FOO</span></span></code></pre></td></tr></table>
+</div>
+`, format(New(WithClasses(true), WithPreCode(true), WithLineNumbers(true), LineNumbersInTable(true))))
+}
+
+func TestLineNumbersRight(t *testing.T) {
+	format := func(f *Formatter) string {
+		it, err := lexers.Get("bash").Tokenise(nil, "echo FOO\necho BAR\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = f.Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("Inline", func(t *testing.T) {
+		s := format(New(WithClasses(true), WithLineNumbers(true), WithLineNumbersRight(true)))
+		assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nb">echo</span> FOO
+</span><span class="ln">1</span></span><span class="line"><span class="cl"><span class="nb">echo</span> BAR
+</span><span class="ln">2</span></span></code></pre>`, s)
+	})
+
+	t.Run("Table", func(t *testing.T) {
+		s := format(New(WithClasses(true), WithLineNumbers(true), LineNumbersInTable(true), WithLineNumbersRight(true)))
+		assert.Equal(t, `<div class="chroma">
+<table class="lntable"><tr><td class="lntd">
+<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nb">echo</span> FOO
+</span></span><span class="line"><span class="cl"><span class="nb">echo</span> BAR
+</span></span></code></pre></td>
+<td class="lntd">
+<pre tabindex="0" class="chroma"><span class="lnt">1
+</span><span class="lnt">2
+</span></pre></td></tr></table>
+</div>
+`, s)
+	})
+
+	t.Run("CSS", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := New(WithClasses(true), WithLineNumbers(true), LineNumbersInTable(true), WithLineNumbersRight(true)).WriteCSS(&buf, styles.Fallback)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `.chroma .lntd:first-child { width: 100%; }`)
+		assert.Contains(t, buf.String(), `.chroma .ln { white-space: pre; user-select: none; margin-left: 0.4em;`)
+	})
+}
+
+func TestInlineCode(t *testing.T) {
+	f := New(WithClasses(true), InlineCode(true), WithLineNumbers(true))
+	it, err := lexers.Get("go").Tokenise(nil, "x := 1")
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = f.Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Equal(t, `<code class="chroma"><span class="nx">x</span> <span class="o">:=</span> <span class="mi">1</span></code>`, buf.String())
+
+	it, err = lexers.Get("go").Tokenise(nil, "x := 1\ny := 2\n")
+	assert.NoError(t, err)
+	err = f.Format(&bytes.Buffer{}, styles.Fallback, it)
+	assert.Error(t, err)
+}
+
+func TestMergeSpans(t *testing.T) {
+	it, err := lexers.Get("go").Tokenise(nil, "x := a+-b\n")
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = New(WithClasses(true), MergeSpans(true)).Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nx">x</span> <span class="o">:=</span> <span class="nx">a</span><span class="o">+-</span><span class="nx">b</span>
+</span></span></code></pre>`, buf.String())
+
+	it, err = lexers.Get("go").Tokenise(nil, "a+-b\n")
+	assert.NoError(t, err)
+	buf.Reset()
+	err = New(WithClasses(true), MergeSpans(true), WithCursor(2)).Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `<span class="o">+</span><span class="cur"><span class="o">-</span></span><span class="nx">b</span>`)
+}
+
+func TestIndentGuides(t *testing.T) {
+	it, err := lexers.Get("go").Tokenise(nil, "if a {\n    if b {\n\t\tc()\n    }\n}\n")
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = New(WithClasses(true), TabWidth(4), IndentGuides(4, "#ccc")).Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="k">if</span> <span class="nx">a</span> <span class="p">{</span>
+</span></span><span class="line"><span class="cl"><span class="ig">    </span><span class="k">if</span> <span class="nx">b</span> <span class="p">{</span>
+</span></span><span class="line"><span class="cl"><span class="ig">	</span><span class="ig">	</span><span class="nf">c</span><span class="p">(</span><span class="p">)</span>
+</span></span><span class="line"><span class="cl"><span class="ig">    </span><span class="p">}</span>
+</span></span><span class="line"><span class="cl"><span class="p">}</span>
+</span></span></code></pre>`, buf.String())
+
+	buf.Reset()
+	err = New(WithClasses(true), IndentGuides(4, "#ccc")).WriteCSS(&buf, styles.Fallback)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `.chroma .ig { box-shadow: 1px 0 0 0 #ccc inset }`)
+
+	it, err = lexers.Get("go").Tokenise(nil, "  x\n")
+	assert.NoError(t, err)
+	buf.Reset()
+	err = New(IndentGuides(2, "red")).Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `<span style="box-shadow: 1px 0 0 0 red inset">  </span>`)
+}
+
+func TestHighlightGutterMarker(t *testing.T) {
+	format := func(options ...Option) string {
+		it, err := lexers.Get("bash").Tokenise(nil, "echo FOO\necho BAR\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		options = append(options, WithClasses(true), HighlightLines([][2]int{{2, 2}}), HighlightGutterMarker("#f00"))
+		err = New(options...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("Inline", func(t *testing.T) {
+		assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="ln">1</span><span class="cl"><span class="nb">echo</span> FOO
+</span></span><span class="line"><span class="gm"><span class="ln">2</span></span><span class="cl"><span class="nb">echo</span> BAR
+</span></span></code></pre>`, format(WithLineNumbers(true)))
+	})
+
+	t.Run("Table", func(t *testing.T) {
+		s := format(WithLineNumbers(true), LineNumbersInTable(true))
+		assert.Contains(t, s, `<span class="lnt">1
+</span><span class="gm"><span class="lnt">2
+</span></span>`)
+		assert.NotContains(t, s, `class="hl"`)
+	})
+
+	t.Run("NoLineNumbers", func(t *testing.T) {
+		assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nb">echo</span> FOO
+</span></span><span class="line gm"><span class="cl"><span class="nb">echo</span> BAR
+</span></span></code></pre>`, format())
+	})
+
+	t.Run("CSS", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := New(WithClasses(true), HighlightGutterMarker("#f00")).WriteCSS(&buf, styles.Fallback)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `.chroma .gm { box-shadow: inset 3px 0 0 0 #f00 }`)
+	})
+}
+
+func TestWithAriaLabels(t *testing.T) {
+	format := func(options ...Option) string {
+		it, err := lexers.Get("go").Tokenise(nil, "var s = \"x\" // c\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(append(options, WithClasses(true))...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	assert.NotContains(t, format(), "aria-label")
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="kd" aria-label="keyword declaration">var</span> <span class="nx">s</span> <span class="p">=</span> <span class="s" aria-label="literal string">&#34;x&#34;</span> <span class="c1" aria-label="comment">// c
+</span></span></span></code></pre>`, format(WithAriaLabels(chroma.KeywordDeclaration, chroma.LiteralString, chroma.Comment)))
+}
+
+func TestWithLanguage(t *testing.T) {
+	format := func(options ...Option) string {
+		it, err := lexers.Get("go").Tokenise(nil, "x")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(append(options, WithClasses(true))...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	assert.NotContains(t, format(), "data-lang")
+	assert.Equal(t, `<pre tabindex="0" class="chroma" data-lang="go"><code><span class="line"><span class="cl"><span class="nx">x</span></span></span></code></pre>`, format(WithLanguage("go")))
+	assert.Equal(t, `<code class="chroma" data-lang="c++"><span class="nx">x</span></code>`, format(WithLanguage("c++"), InlineCode(true)))
+}
+
+func TestContextView(t *testing.T) {
+	format := func(options ...Option) string {
+		it, err := lexers.Get("plaintext").Tokenise(nil, "one\ntwo\nthree\nfour\nfive\nsix\nseven\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(options...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="ln"> </span><span class="cl"><span class="c">… (2 earlier lines)</span></span></span><span class="line"><span class="ln">3</span><span class="cl dim">three
+</span></span><span class="line"><span class="ln">4</span><span class="cl">four
+</span></span><span class="line"><span class="ln">5</span><span class="cl dim">five
+</span></span><span class="line"><span class="ln"> </span><span class="cl"><span class="c">… (2 more lines)</span></span></span></code></pre>`,
+		format(ContextView([2]int{4, 4}, 1, 1), WithLineNumbers(true), WithClasses(true)))
+
+	// Negative context includes all surrounding lines.
+	assert.Equal(t, `<div class="chroma">
+<table class="lntable"><tr><td class="lntd">
+<pre tabindex="0" class="chroma"><span class="lnt">1
+</span><span class="lnt">2
+</span><span class="lnt">3
+</span><span class="lnt"> 
+</span></pre></td>
+<td class="lntd">
+<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl dim">one
+</span></span><span class="line"><span class="cl">two
+</span></span><span class="line"><span class="cl">three
+</span></span><span class="line"><span class="cl"><span class="c">… (4 more lines)</span></span></span></code></pre></td></tr></table>
+</div>
+`, format(ContextView([2]int{2, 3}, -1, 0), WithLineNumbers(true), LineNumbersInTable(true), WithClasses(true)))
+
+	assert.Contains(t, format(ContextView([2]int{2, 3}, -1, -1)), `<span style="opacity:0.5">one`)
+	css := &strings.Builder{}
+	assert.NoError(t, New(WithClasses(true), ContextView([2]int{1, 1}, 0, 0)).WriteCSS(css, styles.Fallback))
+	assert.Contains(t, css.String(), "/* ContextDim */ .chroma .dim { opacity:0.5 }")
+}
+
+func TestWithValueRewriter(t *testing.T) {
+	mask := func(token chroma.Token) string {
+		if token.Type.InCategory(chroma.LiteralString) && len(token.Value) > 2 {
+			return token.Value[:1] + strings.Repeat("*", len(token.Value)-2) + token.Value[len(token.Value)-1:]
+		}
+		return token.Value
+	}
+	it, err := lexers.Get("go").Tokenise(nil, `key := "sk-123"`)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = New(WithClasses(true), PreventSurroundingPre(true), WithValueRewriter(mask)).Format(&buf, styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Equal(t, `<span class="line"><span class="cl"><span class="nx">key</span> <span class="o">:=</span> <span class="s">&#34;******&#34;</span></span></span>`, buf.String())
+
+	it, err = lexers.Get("go").Tokenise(nil, `key := "sk-123"`)
+	assert.NoError(t, err)
+	_, text, err := New(WithValueRewriter(mask)).FormatToStrings(styles.Fallback, it)
+	assert.NoError(t, err)
+	assert.Equal(t, `key := "******"`, text)
+}
+
+func TestLineNumberTitles(t *testing.T) {
+	format := func(options ...Option) string {
+		it, err := lexers.Get("plaintext").Tokenise(nil, "if a < b {\n\treturn \"a very long line\"\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(append(options, WithClasses(true), WithLineNumbers(true))...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	assert.NotContains(t, format(), "title=")
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="ln" title="if a &lt; b {">1</span><span class="cl">if a &lt; b {
+</span></span><span class="line"><span class="ln" title="	return &#34;a very…">2</span><span class="cl">	return &#34;a very long line&#34;
+</span></span></code></pre>`, format(LineNumberTitles(16)))
+	assert.Contains(t, format(LineNumberTitles(16), LineNumbersInTable(true)), `<span class="lnt" title="if a &lt; b {">1
+</span>`)
+}
+
+func TestWithGutter(t *testing.T) {
+	blame := map[int]string{1: "alice 2021-01-02", 2: "bob <b@x>"}
+	gutter := WithGutter(func(line int) string { return blame[line] })
+	format := func(options ...Option) string {
+		it, err := lexers.Get("plaintext").Tokenise(nil, "a\nb\nc\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(append(options, WithClasses(true))...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="ln gt">alice 2021-01-02</span><span class="ln">1</span><span class="cl">a
+</span></span><span class="line"><span class="ln gt">bob &lt;b@x&gt;       </span><span class="ln">2</span><span class="cl">b
+</span></span><span class="line"><span class="ln gt">                </span><span class="ln">3</span><span class="cl">c
+</span></span></code></pre>`, format(gutter, WithLineNumbers(true)))
+
+	// Without line numbers.
+	assert.Contains(t, format(gutter), `<span class="line"><span class="ln gt">alice 2021-01-02</span><span class="cl">a`)
+
+	// In a table the gutter is its own column.
+	assert.Contains(t, format(gutter, WithLineNumbers(true), LineNumbersInTable(true)),
+		`<table class="lntable"><tr><td class="lntd">
+<pre tabindex="0" class="chroma"><span class="ln gt">alice 2021-01-02
+</span><span class="ln gt">bob &lt;b@x&gt;       
+</span><span class="ln gt">                
+</span></pre></td>
+<td class="lntd">
+<pre tabindex="0" class="chroma"><span class="lnt">1
+`)
+}
+
+func TestAlignTabs(t *testing.T) {
+	format := func(options ...Option) string {
+		it, err := lexers.Get("plaintext").Tokenise(nil, "id\tname\tqty\n1\tapple\t10\n")
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(options...).Format(&buf, styles.Fallback, it)
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	// Each tab reaches the next stop at 8 columns, so the columns line up.
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl">id<span class="ts6">	</span>name<span class="ts4">	</span>qty
+</span></span><span class="line"><span class="cl">1<span class="ts7">	</span>apple<span class="ts3">	</span>10
+</span></span></code></pre>`, format(WithClasses(true), AlignTabs(true)))
+
+	assert.Contains(t, format(AlignTabs(true), TabWidth(4)),
+		`1<span style="display:inline-block;width:3ch;overflow:hidden;vertical-align:top">	</span>apple<span style="display:inline-block;width:3ch;overflow:hidden;vertical-align:top">	</span>10`)
+
+	var css bytes.Buffer
+	err := New(WithClasses(true), AlignTabs(true), TabWidth(2)).WriteCSS(&css, styles.Fallback)
+	assert.NoError(t, err)
+	assert.Contains(t, css.String(), "/* TabStop */ .chroma .ts1 { display:inline-block;width:1ch;overflow:hidden;vertical-align:top }\n"+
+		"/* TabStop */ .chroma .ts2 { display:inline-block;width:2ch;overflow:hidden;vertical-align:top }\n")
+}
+
+func TestFlushLines(t *testing.T) {
+	it, err := lexers.Get("go").Tokenise(nil, "package main\n\nfunc main() {}\n")
+	assert.NoError(t, err)
+	tokens := it.Tokens()
+
+	var lines []int
+	flushed := ""
+	var buf bytes.Buffer
+	err = New(WithClasses(true), WithLineNumbers(true), FlushLines(func(line int, html string) error {
+		lines = append(lines, line)
+		flushed += html
+		assert.True(t, strings.HasSuffix(html, "</span></span>"), html)
+		return nil
+	})).Format(&buf, styles.Fallback, chroma.Literator(tokens...))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, lines)
+
+	var full bytes.Buffer
+	err = New(WithClasses(true), WithLineNumbers(true)).Format(&full, styles.Fallback, chroma.Literator(tokens...))
+	assert.NoError(t, err)
+	assert.Equal(t, full.String(), buf.String())
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code>`+flushed+`</code></pre>`, full.String())
+
+	// Errors from the callback stop formatting.
+	errStop := errors.New("client went away")
+	buf.Reset()
+	err = New(FlushLines(func(line int, html string) error {
+		if line == 2 {
+			return errStop
+		}
+		return nil
+	})).Format(&buf, styles.Fallback, chroma.Literator(tokens...))
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 1, strings.Count(buf.String(), "package"))
+	assert.NotContains(t, buf.String(), "func")
+}
+
+func TestHighlightLinesSplitsMultilineTokens(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "x"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.CommentMultiline, Value: "/* one\ntwo\nthree */"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	var buf bytes.Buffer
+	err := New(WithClasses(true), HighlightLines([][2]int{{2, 2}})).Format(&buf, styles.Fallback, chroma.Literator(tokens...))
+	assert.NoError(t, err)
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="k">x</span> <span class="cm">/* one
+</span></span></span><span class="line hl"><span class="cl"><span class="cm">two
+</span></span></span><span class="line"><span class="cl"><span class="cm">three */</span>
+</span></span></code></pre>`, buf.String())
+}
+
+func TestScopedCSS(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background:   "#000000 bg:#ffffff",
+		chroma.Keyword:      "bold #0000ff",
+		chroma.NameFunction: "#00ff00",
+		chroma.Comment:      "italic #888888",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.KeywordDeclaration, Value: "func"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.NameFunction, Value: "main"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	var buf bytes.Buffer
+	err := New(ScopedCSS(true)).Format(&buf, style, chroma.Literator(tokens...))
+	assert.NoError(t, err)
+	assert.Equal(t, `<style>
+/* PreWrapper */ .c120d9ee2-chroma { color: #000000; background-color: #ffffff; }
+/* Line */ .c120d9ee2-chroma .c120d9ee2-line { display: flex; }
+/* KeywordDeclaration */ .c120d9ee2-chroma .c120d9ee2-kd { color: #0000ff; font-weight: bold }
+/* NameFunction */ .c120d9ee2-chroma .c120d9ee2-nf { color: #00ff00 }
+</style>
+<pre tabindex="0" class="c120d9ee2-chroma"><code><span class="c120d9ee2-line"><span class="c120d9ee2-cl"><span class="c120d9ee2-kd">func</span> <span class="c120d9ee2-nf">main</span>
+</span></span></code></pre>`, buf.String())
+
+	// Blocks with different styles use different prefixes, so that their rules
+	// do not override each other on the same page.
+	prefix := func(style *chroma.Style, options ...Option) string {
+		var buf bytes.Buffer
+		err := New(append(options, ScopedCSS(true))...).Format(&buf, style, chroma.Literator(tokens...))
+		assert.NoError(t, err)
+		match := regexp.MustCompile(`class="(\S*)chroma"`).FindStringSubmatch(buf.String())
+		assert.NotNil(t, match)
+		return match[1]
+	}
+	assert.Equal(t, prefix(style), prefix(style))
+	assert.NotEqual(t, prefix(style), prefix(styles.Get("monokai")))
+	assert.Equal(t, "my-", prefix(style, ClassPrefix("my-")))
+}
+
+func TestWithDefaultStyle(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background: "#000000 bg:#ffffff",
+		chroma.Keyword:    "bold #0000ff",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "return"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Name, Value: "x"},
+	}
+	format := func(options ...Option) string {
+		var buf bytes.Buffer
+		err := New(options...).Format(&buf, style, chroma.Literator(tokens...))
+		assert.NoError(t, err)
+		return buf.String()
+	}
+	assert.Equal(t, `<pre tabindex="0" style="color:#000;background-color:#fff;"><code><span style="display:flex;"><span><span style="color:#00f;font-weight:bold">return</span> x</span></span></code></pre>`, format())
+	// Plain text is recoloured, keywords are unaffected.
+	orange := chroma.StyleEntry{Colour: chroma.MustParseColour("#ff8800")}
+	assert.Equal(t, `<pre tabindex="0" style="color:#f80;background-color:#fff;"><code><span style="display:flex;"><span><span style="color:#00f;font-weight:bold">return</span> x</span></span></code></pre>`, format(WithDefaultStyle(orange)))
+
+	var css bytes.Buffer
+	err := New(WithClasses(true), WithDefaultStyle(orange)).WriteCSS(&css, style)
+	assert.NoError(t, err)
+	assert.Contains(t, css.String(), "/* Background */ .bg { color: #ff8800; background-color: #ffffff }\n")
+}
+
+func TestWithXHTML(t *testing.T) {
+	source := "package main\n\n// a < b && 'c'\nfunc main() {\n\tx := \"y\"\n}\n"
+	tests := [][]Option{
+		{Standalone(true)},
+		{Standalone(true), WithClasses(true), WithLineNumbers(true), LineNumbersInTable(true)},
+		{WithClasses(true), ScopedCSS(true), WithLineNumbers(true), LinkableLineNumbers(true, "L"), HighlightLines([][2]int{{2, 3}})},
+		{WithCursor(3), WithLanguageBadge("Go"), IndentGuides(4, "#ccc")},
+	}
+	for i, options := range tests {
+		it, err := lexers.Get("go").Tokenise(nil, source)
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		err = New(append(options, WithXHTML(true))...).Format(&buf, styles.Get("monokai"), it)
+		assert.NoError(t, err)
+		out := buf.String()
+		if !strings.HasPrefix(out, "<?xml") {
+			out = "<root>" + out + "</root>"
+		}
+		decoder := xml.NewDecoder(strings.NewReader(out))
+		for {
+			_, err = decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err, "%d: %s", i, out) {
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := New(Standalone(true), WithXHTML(true)).Format(&buf, styles.Fallback, chroma.Literator(chroma.Token{Type: chroma.Text, Value: "x\n"}))
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `<html xmlns="http://www.w3.org/1999/xhtml">`)
+}
+
+func TestSingleElementLines(t *testing.T) {
+	it, err := lexers.Get("go").Tokenise(nil, "x := a+-b\n\tif\n")
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = New(WithClasses(true), SingleElementLines(true)).Format(&buf, styles.Get("monokai"), it)
+	assert.NoError(t, err)
+	assert.Equal(t, `<pre tabindex="0" class="chroma"><code>`+
+		`<span class="line"><span class="cl" style="background:linear-gradient(to right,#a6e22e 0ch 1ch,#f8f8f2 1ch 2ch,#f92672 2ch 4ch,#f8f8f2 4ch 5ch,#a6e22e 5ch 6ch,#f92672 6ch 8ch,#a6e22e 8ch 9ch);-webkit-background-clip:text;background-clip:text;color:transparent">x := a+-b
+</span></span>`+
+		`<span class="line"><span class="cl" style="background:linear-gradient(to right,#f8f8f2 0ch 8ch,#66d9ef 8ch 10ch);-webkit-background-clip:text;background-clip:text;color:transparent">	if
+</span></span>`+
+		`</code></pre>`, buf.String())
+
+	// Without classes the code line has no other style.
+	it, err = lexers.Get("go").Tokenise(nil, "x\n")
+	assert.NoError(t, err)
+	buf.Reset()
+	err = New(SingleElementLines(true)).Format(&buf, styles.Get("monokai"), it)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `<span style="display:flex;"><span style="background:linear-gradient(to right,#a6e22e 0ch 1ch);-webkit-background-clip:text;background-clip:text;color:transparent">x
+</span></span>`)
+}
@@ -0,0 +1,70 @@
+package formatters
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// ErrOutputLimit is returned by formatters created with LimitOutput when their
+// output was truncated.
+var ErrOutputLimit = errors.New("output limit reached")
+
+// OutputTruncationMarker is appended to output truncated by LimitOutput.
+const OutputTruncationMarker = "\n… (output truncated)\n"
+
+// LimitOutput wraps inner so that at most maxBytes of formatted output are
+// written, followed by OutputTruncationMarker if the limit was reached, in
+// which case ErrOutputLimit is returned.
+//
+// Output is only ever cut on a UTF-8 character boundary, but may otherwise be
+// cut mid-element, eg. within an HTML tag.
+func LimitOutput(inner chroma.Formatter, maxBytes int) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		lw := &limitWriter{w: w, remaining: maxBytes}
+		err := inner.Format(lw, style, it)
+		if lw.err != nil {
+			return lw.err
+		}
+		if lw.truncated {
+			if _, err := io.WriteString(w, OutputTruncationMarker); err != nil {
+				return err
+			}
+			return ErrOutputLimit
+		}
+		return err
+	})
+}
+
+type limitWriter struct {
+	w         io.Writer
+	remaining int
+	truncated bool
+	err       error // Error from w.
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.truncated {
+		return 0, ErrOutputLimit
+	}
+	n := len(p)
+	if n > l.remaining {
+		n = l.remaining
+		for n > 0 && !utf8.RuneStart(p[n]) {
+			n--
+		}
+		l.truncated = true
+	}
+	written, err := l.w.Write(p[:n])
+	l.remaining -= written
+	if err != nil {
+		l.err = err
+		return written, err
+	}
+	if l.truncated {
+		return written, ErrOutputLimit
+	}
+	return written, nil
+}
@@ -0,0 +1,42 @@
+package formatters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestLimitOutput(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "func"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Name, Value: "héllo"},
+	}
+	format := func(maxBytes int) (string, error) {
+		buf := &bytes.Buffer{}
+		err := LimitOutput(NoOp, maxBytes).Format(buf, nil, chroma.Literator(tokens...))
+		return buf.String(), err
+	}
+
+	// Exactly at the limit.
+	out, err := format(11)
+	require.NoError(t, err)
+	assert.Equal(t, "func héllo", out)
+
+	out, err = format(10)
+	assert.Equal(t, ErrOutputLimit, err)
+	assert.Equal(t, "func héll"+OutputTruncationMarker, out)
+
+	// Never split a multi-byte character.
+	out, err = format(7)
+	assert.Equal(t, ErrOutputLimit, err)
+	assert.Equal(t, "func h"+OutputTruncationMarker, out)
+
+	out, err = format(0)
+	assert.Equal(t, ErrOutputLimit, err)
+	assert.Equal(t, OutputTruncationMarker, out)
+}
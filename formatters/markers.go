@@ -0,0 +1,60 @@
+package formatters
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Markers formatter outputs plain text with each token wrapped in markers
+// named after its CSS class, eg. "⟦kd⟧func⟦/kd⟧". Text and Whitespace are
+// not marked.
+var Markers = Register("markers", NewMarkers(nil))
+
+// NewMarkers creates a formatter that outputs plain text with the value of
+// each token wrapped in the open and close markers for its type, eg.
+//
+//	NewMarkers(map[chroma.TokenType][2]string{chroma.Keyword: {"<kw>", "</kw>"}})
+//
+// Types without markers use those of their closest ancestor, and are otherwise
+// written unmarked. If markers is nil, the default markers of the Markers
+// formatter are used.
+func NewMarkers(markers map[chroma.TokenType][2]string) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, s *chroma.Style, it chroma.Iterator) error {
+		for t := it(); t != chroma.EOF; t = it() {
+			open, close, ok := markersFor(markers, t.Type)
+			if !ok || t.Value == "" {
+				if _, err := io.WriteString(w, t.Value); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%s", open, t.Value, close); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func markersFor(markers map[chroma.TokenType][2]string, tt chroma.TokenType) (open, close string, ok bool) {
+	if markers == nil {
+		if tt == chroma.Text || tt == chroma.Whitespace {
+			return "", "", false
+		}
+		class := chroma.StandardTypes[tt]
+		if class == "" {
+			return "", "", false
+		}
+		return "⟦" + class + "⟧", "⟦/" + class + "⟧", true
+	}
+	for ; ; tt = tt.Parent() {
+		if marker, ok := markers[tt]; ok {
+			return marker[0], marker[1], true
+		}
+		if tt == 0 {
+			return "", "", false
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package formatters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestMarkers(t *testing.T) {
+	tokens := []chroma.Token{
+		{chroma.KeywordDeclaration, "func"},
+		{chroma.Whitespace, " "},
+		{chroma.NameFunction, "main"},
+		{chroma.Punctuation, "()"},
+		{chroma.Text, "\n"},
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, Markers.Format(buf, nil, chroma.Literator(tokens...)))
+	assert.Equal(t, "⟦kd⟧func⟦/kd⟧ ⟦nf⟧main⟦/nf⟧⟦p⟧()⟦/p⟧\n", buf.String())
+
+	buf.Reset()
+	formatter := NewMarkers(map[chroma.TokenType][2]string{
+		chroma.Keyword: {"<kw>", "</kw>"},
+		chroma.Name:    {"[", "]"},
+	})
+	require.NoError(t, formatter.Format(buf, nil, chroma.Literator(tokens...)))
+	assert.Equal(t, "<kw>func</kw> [main]()\n", buf.String())
+}
@@ -0,0 +1,40 @@
+package formatters
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Pager wraps a terminal formatter for output piped to a pager such as
+// `less -R`.
+//
+// Tokens are split at newlines so that no escape sequence spans a line, and
+// every line ends with a reset before its newline. Each line is thus rendered
+// correctly regardless of where the pager starts drawing. No cursor movement
+// sequences are emitted.
+func Pager(formatter chroma.Formatter) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		for _, line := range chroma.SplitTokensIntoLines(it.Tokens()) {
+			newline := ""
+			if n := len(line); n > 0 && strings.HasSuffix(line[n-1].Value, "\n") {
+				last := line[n-1]
+				last.Value = strings.TrimSuffix(last.Value, "\n")
+				line = line[: n-1 : n-1]
+				if last.Value != "" {
+					line = append(line, last)
+				}
+				newline = "\n"
+			}
+			if err := formatter.Format(w, style, chroma.Literator(line...)); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprint(w, "\033[0m"+newline); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package formatters
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestPager(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.CommentMultiline, Value: "/* one\ntwo */"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.Keyword, Value: "func"},
+		{Type: chroma.Text, Value: "\n\n"},
+	}
+	escapeRe := regexp.MustCompile("\033\\[[^a-zA-Z]*[a-zA-Z]")
+	for _, formatter := range []chroma.Formatter{TTY8, TTY256, TTY16m} {
+		buf := &bytes.Buffer{}
+		err := Pager(formatter).Format(buf, styles.Get("monokai"), chroma.Literator(tokens...))
+		require.NoError(t, err)
+		lines := strings.Split(buf.String(), "\n")
+		assert.Equal(t, "", lines[len(lines)-1])
+		lines = lines[:len(lines)-1]
+		assert.Len(t, lines, 4)
+		for _, line := range lines {
+			assert.True(t, strings.HasSuffix(line, "\033[0m"), "%q", line)
+			// Only SGR sequences are permitted, ie. no cursor movement.
+			for _, esc := range escapeRe.FindAllString(line, -1) {
+				assert.Regexp(t, "^\033\\[[0-9;]*m$", esc)
+			}
+		}
+		plain := escapeRe.ReplaceAllString(buf.String(), "")
+		assert.Equal(t, chroma.Stringify(tokens...), plain)
+		assert.Contains(t, lines[1], "two */")
+		assert.NotEqual(t, "two */\033[0m", lines[1], "styling should be re-applied on each line")
+	}
+}
@@ -0,0 +1,59 @@
+package formatters
+
+import (
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// PageSeparator is written between pages by Paginate, a form feed.
+const PageSeparator = "\f"
+
+// Paginate wraps formatter, splitting output into pages of linesPerPage lines
+// separated by PageSeparator.
+//
+// Each page is formatted independently, so any state such as terminal colours
+// or HTML wrappers is re-established at the start of each page. Tokens
+// spanning several lines are split at page boundaries.
+func Paginate(formatter chroma.Formatter, linesPerPage int) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		pages, err := Pages(formatter, style, it, linesPerPage)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, strings.Join(pages, PageSeparator))
+		return err
+	})
+}
+
+// Pages formats the tokens of it with formatter in pages of linesPerPage lines,
+// returning each rendered page. If linesPerPage is <= 0, a single page is
+// returned.
+func Pages(formatter chroma.Formatter, style *chroma.Style, it chroma.Iterator, linesPerPage int) ([]string, error) {
+	lines := chroma.SplitTokensIntoLines(it.Tokens())
+	if linesPerPage <= 0 {
+		linesPerPage = len(lines)
+	}
+	pages := []string{}
+	for start := 0; start < len(lines); start += linesPerPage {
+		end := start + linesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		var tokens []chroma.Token
+		for _, line := range lines[start:end] {
+			for _, token := range line {
+				if token.Value != "" {
+					tokens = append(tokens, token)
+				}
+			}
+		}
+		page := &strings.Builder{}
+		if err := formatter.Format(page, style, chroma.Literator(tokens...)); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page.String())
+	}
+	return pages, nil
+}
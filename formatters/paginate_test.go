@@ -0,0 +1,36 @@
+package formatters
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestPaginate(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "a"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.CommentMultiline, Value: "/* b\nc */"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.Keyword, Value: "d"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	pages, err := Pages(NoOp, nil, chroma.Literator(tokens...), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a\n/* b\n", "c */\nd\n"}, pages)
+
+	// The comment colour is re-established at the start of the second page.
+	buf := &bytes.Buffer{}
+	require.NoError(t, Paginate(TTY16m, 2).Format(buf, styles.Get("monokai"), chroma.Literator(tokens...)))
+	pages = strings.Split(buf.String(), PageSeparator)
+	require.Len(t, pages, 2)
+	comment := "\033[38;2;117;113;94m"
+	assert.True(t, strings.HasPrefix(pages[1], comment+"c */"), "%q", pages[1])
+	assert.True(t, strings.HasSuffix(pages[0], "\033[0m"), "%q", pages[0])
+}
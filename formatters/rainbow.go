@@ -0,0 +1,66 @@
+package formatters
+
+import (
+	"github.com/alecthomas/chroma/v2"
+)
+
+// PaletteMode controls how RainbowBrackets picks a colour for brackets nested
+// deeper than its palette.
+type PaletteMode int
+
+const (
+	// PaletteCycle wraps around to the start of the palette.
+	PaletteCycle PaletteMode = iota
+	// PaletteClamp uses the last entry of the palette.
+	PaletteClamp
+)
+
+var rainbowBrackets = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+// RainbowBrackets returns a DecorationProvider that styles brackets within
+// Punctuation tokens by nesting depth, using palette[0] for the outermost
+// level.
+//
+// Each type of bracket is nested independently, so the depth of "(" is not
+// affected by enclosing "[" or "{". Unbalanced closing brackets are not
+// decorated.
+func RainbowBrackets(palette []chroma.StyleEntry, mode PaletteMode) DecorationProvider {
+	return func(tokens []chroma.Token) []Decoration {
+		decorations := []Decoration{}
+		if len(palette) == 0 {
+			return decorations
+		}
+		depths := map[byte]int{}
+		entry := func(depth int) chroma.StyleEntry {
+			if depth >= len(palette) {
+				if mode == PaletteClamp {
+					return palette[len(palette)-1]
+				}
+				depth %= len(palette)
+			}
+			return palette[depth]
+		}
+		offset := 0
+		for _, token := range tokens {
+			if token.Type.InCategory(chroma.Punctuation) {
+				for i := 0; i < len(token.Value); i++ {
+					c := token.Value[i]
+					switch c {
+					case '(', '[', '{':
+						decorations = append(decorations, Decoration{Start: offset + i, End: offset + i + 1, Style: entry(depths[c])})
+						depths[c]++
+					case ')', ']', '}':
+						open := rainbowBrackets[c]
+						if depths[open] == 0 {
+							continue
+						}
+						depths[open]--
+						decorations = append(decorations, Decoration{Start: offset + i, End: offset + i + 1, Style: entry(depths[open])})
+					}
+				}
+			}
+			offset += len(token.Value)
+		}
+		return decorations
+	}
+}
@@ -0,0 +1,45 @@
+package formatters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestRainbowBrackets(t *testing.T) {
+	red := chroma.StyleEntry{Colour: chroma.MustParseColour("#f00")}
+	green := chroma.StyleEntry{Colour: chroma.MustParseColour("#0f0")}
+	palette := []chroma.StyleEntry{red, green}
+	tokens := []chroma.Token{
+		{chroma.Punctuation, "((["},
+		{chroma.LiteralString, "(("},
+		{chroma.Punctuation, "("},
+		{chroma.Name, "x"},
+		{chroma.Punctuation, ")])))"},
+	}
+
+	styles := func(decorations []Decoration) []chroma.StyleEntry {
+		out := []chroma.StyleEntry{}
+		for _, d := range decorations {
+			out = append(out, d.Style)
+		}
+		return out
+	}
+	// Brackets at 0, 1, 2, 5, 7, 8, 9, 10 and the unbalanced one at 11.
+	decorations := RainbowBrackets(palette, PaletteCycle)(tokens)
+	assert.Equal(t, []Decoration{
+		{Start: 0, End: 1, Style: red},
+		{Start: 1, End: 2, Style: green},
+		{Start: 2, End: 3, Style: red},
+		{Start: 5, End: 6, Style: red},
+		{Start: 7, End: 8, Style: red},
+		{Start: 8, End: 9, Style: red},
+		{Start: 9, End: 10, Style: green},
+		{Start: 10, End: 11, Style: red},
+	}, decorations)
+
+	decorations = RainbowBrackets(palette, PaletteClamp)(tokens)
+	assert.Equal(t, []chroma.StyleEntry{red, green, red, green, green, red, green, red}, styles(decorations))
+}
@@ -0,0 +1,75 @@
+package formatters
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Rich formatter outputs console markup for the Python Rich and Textual
+// libraries, eg. "[bold #ff0000]func[/]".
+var Rich = Register("rich", chroma.FormatterFunc(richFormatter))
+
+func richFormatter(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+	style = clearBackground(style)
+	for token := it(); token != chroma.EOF; token = it() {
+		tag := richTag(style.Get(token.Type))
+		value := escapeRich(token.Value)
+		if tag != "" {
+			value = fmt.Sprintf("[%s]%s[/]", tag, value)
+		}
+		if _, err := fmt.Fprint(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// richTag converts a StyleEntry to the contents of a Rich markup tag.
+func richTag(entry chroma.StyleEntry) string {
+	out := []string{}
+	if entry.Bold == chroma.Yes {
+		out = append(out, "bold")
+	}
+	if entry.Italic == chroma.Yes {
+		out = append(out, "italic")
+	}
+	if entry.Underline == chroma.Yes {
+		out = append(out, "underline")
+	}
+	if entry.Colour.IsSet() {
+		out = append(out, entry.Colour.String())
+	}
+	if entry.Background.IsSet() {
+		out = append(out, "on "+entry.Background.String())
+	}
+	return strings.Join(out, " ")
+}
+
+// escapeRich escapes literal brackets in s with a backslash. Backslashes
+// preceding a bracket, or the end of s where a closing tag may follow, are
+// doubled so that they remain literal.
+func escapeRich(s string) string {
+	if !strings.ContainsAny(s, `[\`) {
+		return s
+	}
+	out := strings.Builder{}
+	backslashes := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			backslashes++
+			continue
+		case '[':
+			out.WriteString(strings.Repeat(`\`, backslashes*2+1))
+		default:
+			out.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		out.WriteByte(s[i])
+	}
+	out.WriteString(strings.Repeat(`\`, backslashes*2))
+	return out.String()
+}
@@ -0,0 +1,46 @@
+package formatters
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestRichFormatter(t *testing.T) {
+	source := "package main\n\n// Print [x].\nfunc main() {\n\tfmt.Println(a[0], \"\\\\\")\n}\n"
+	it, err := lexers.Get("go").Tokenise(nil, source)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	err = Get("rich").Format(buf, styles.Get("monokai"), it)
+	require.NoError(t, err)
+
+	golden := "testdata/rich.golden"
+	if os.Getenv("RECORD") == "true" {
+		require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0600))
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), buf.String())
+}
+
+func TestEscapeRich(t *testing.T) {
+	tests := []struct{ in, out string }{
+		{"plain", "plain"},
+		{"a[0]", `a\[0]`},
+		{`\[b]`, `\\\[b]`},
+		{`a\b`, `a\b`},
+		{`end\`, `end\\`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.out, escapeRich(test.in), test.in)
+	}
+	assert.Equal(t, "", richTag(chroma.StyleEntry{}))
+	assert.Equal(t, "bold italic #ff0000 on #000001", richTag(chroma.StyleEntry{Bold: chroma.Yes, Italic: chroma.Yes, Underline: chroma.No, Colour: chroma.MustParseColour("#f00"), Background: chroma.MustParseColour("#000001")}))
+}
@@ -0,0 +1,97 @@
+package formatters
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// A Segment of formatted output, covering StartLine to EndLine inclusive.
+// Lines are numbered from 1.
+type Segment struct {
+	// Label is the name of the top-level construct, eg. a function or class,
+	// or "" for the source between constructs.
+	Label     string
+	StartLine int
+	EndLine   int
+	Output    string
+}
+
+// FormatSegments splits the tokens of it into top-level constructs, such as
+// functions and classes, and formats each separately with formatter.
+//
+// Segmentation is best-effort, using the outermost fold regions described by
+// the Fold hints of config (see chroma.ComputeFoldRegions). Constructs are
+// labelled with the first function or class name on their first line, or
+// otherwise the line itself. Source between constructs is returned in
+// unlabelled segments, so that the segments cover every line.
+func FormatSegments(formatter chroma.Formatter, style *chroma.Style, config *chroma.Config, it chroma.Iterator) ([]Segment, error) {
+	tokens := it.Tokens()
+	lines := chroma.SplitTokensIntoLines(tokens)
+	segments := []Segment{}
+	next := 1
+	for _, region := range chroma.ComputeFoldRegions(config, tokens) {
+		if region.StartLine < next {
+			continue
+		}
+		start := region.StartLine
+		// Allow for the opening bracket on a line of its own.
+		if start > next && isBracketLine(lines[start-1]) && !isBracketLine(lines[start-2]) {
+			start--
+		}
+		if start > next {
+			segments = append(segments, Segment{StartLine: next, EndLine: start - 1})
+		}
+		segments = append(segments, Segment{Label: segmentLabel(lines[start-1 : region.EndLine]), StartLine: start, EndLine: region.EndLine})
+		next = region.EndLine + 1
+	}
+	if next <= len(lines) {
+		segments = append(segments, Segment{StartLine: next, EndLine: len(lines)})
+	}
+	for i, segment := range segments {
+		var segmentTokens []chroma.Token
+		for _, line := range lines[segment.StartLine-1 : segment.EndLine] {
+			for _, token := range line {
+				if token.Value != "" {
+					segmentTokens = append(segmentTokens, token)
+				}
+			}
+		}
+		w := &strings.Builder{}
+		if err := formatter.Format(w, style, chroma.Literator(segmentTokens...)); err != nil {
+			return nil, err
+		}
+		segments[i].Output = w.String()
+	}
+	return segments, nil
+}
+
+func isBracketLine(line []chroma.Token) bool {
+	text := strings.TrimSpace(lineText(line))
+	return text == "{" || text == "(" || text == "["
+}
+
+func lineText(line []chroma.Token) string {
+	text := ""
+	for _, token := range line {
+		text += token.Value
+	}
+	return text
+}
+
+// segmentLabel returns the first function or class name in the first or, if
+// it is only a bracket, second of lines, or otherwise the first line.
+func segmentLabel(lines [][]chroma.Token) string {
+	for i := 0; i < len(lines) && i < 2; i++ {
+		for _, token := range lines[i] {
+			switch token.Type {
+			case chroma.NameFunction, chroma.NameFunctionMagic, chroma.NameClass:
+				return token.Value
+			}
+		}
+		if !isBracketLine(lines[i]) {
+			break
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(lineText(lines[0])), "{(["))
+}
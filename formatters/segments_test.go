@@ -0,0 +1,26 @@
+package formatters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+func TestFormatSegments(t *testing.T) {
+	source := "package main\n\nfunc a() {\n\tb()\n}\n\n// c comment.\nfunc c() int {\n\treturn 1\n}\n"
+	lexer := lexers.Get("go")
+	it, err := lexer.Tokenise(nil, source)
+	require.NoError(t, err)
+	segments, err := FormatSegments(NoOp, styles.Fallback, lexer.Config(), it)
+	require.NoError(t, err)
+	assert.Equal(t, []Segment{
+		{StartLine: 1, EndLine: 2, Output: "package main\n\n"},
+		{Label: "a", StartLine: 3, EndLine: 5, Output: "func a() {\n\tb()\n}\n"},
+		{StartLine: 6, EndLine: 7, Output: "\n// c comment.\n"},
+		{Label: "c", StartLine: 8, EndLine: 10, Output: "func c() int {\n\treturn 1\n}\n"},
+	}, segments)
+}
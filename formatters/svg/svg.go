@@ -45,6 +45,18 @@ func EmbedFont(fontFamily string, font string, format FontFormat) Option {
 	return func(f *Formatter) { f.fontFamily = fontFamily; f.embeddedFont = font; f.fontFormat = format }
 }
 
+// WithLanguageBadge renders a badge containing name, eg. "Go", in a corner of
+// the image. See LanguageBadgeStyle.
+func WithLanguageBadge(name string) Option { return func(f *Formatter) { f.badge = name } }
+
+// LanguageBadgeStyle sets the corner in which the WithLanguageBadge badge is
+// rendered, one of "top-right" (the default), "top-left", "bottom-right" or
+// "bottom-left", and its colours. Unset colours default to the inverse of the
+// style's Background.
+func LanguageBadgeStyle(corner string, colour, background chroma.Colour) Option {
+	return func(f *Formatter) { f.badgeCorner = corner; f.badgeColour = colour; f.badgeBackground = background }
+}
+
 // New SVG formatter.
 func New(options ...Option) *Formatter {
 	f := &Formatter{fontFamily: "Consolas, Monaco, Lucida Console, Liberation Mono, DejaVu Sans Mono, Bitstream Vera Sans Mono, Courier New, monospace"}
@@ -59,9 +71,19 @@ type Formatter struct {
 	fontFamily   string
 	embeddedFont string
 	fontFormat   FontFormat
+
+	badge           string
+	badgeCorner     string
+	badgeColour     chroma.Colour
+	badgeBackground chroma.Colour
 }
 
 func (f *Formatter) Format(w io.Writer, style *chroma.Style, iterator chroma.Iterator) (err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			err = perr.(error)
+		}
+	}()
 	f.writeSVG(w, style, iterator.Tokens())
 	return err
 }
@@ -86,7 +108,11 @@ func (f *Formatter) writeSVG(w io.Writer, style *chroma.Style, tokens []chroma.T
 
 	fmt.Fprint(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
 	fmt.Fprint(w, "<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.0//EN\" \"http://www.w3.org/TR/2001/REC-SVG-20010904/DTD/svg10.dtd\">\n")
-	fmt.Fprintf(w, "<svg width=\"%dpx\" height=\"%dpx\" xmlns=\"http://www.w3.org/2000/svg\">\n", 8*maxLineWidth(lines), 10+int(16.8*float64(len(lines)+1)))
+	width, height := 8*maxLineWidth(lines), 10+int(16.8*float64(len(lines)+1))
+	if badgeWidth := f.badgeWidth(); badgeWidth > width {
+		width = badgeWidth
+	}
+	fmt.Fprintf(w, "<svg width=\"%dpx\" height=\"%dpx\" xmlns=\"http://www.w3.org/2000/svg\">\n", width, height)
 
 	if f.embeddedFont != "" {
 		f.writeFontStyle(w)
@@ -112,9 +138,50 @@ func (f *Formatter) writeSVG(w io.Writer, style *chroma.Style, tokens []chroma.T
 	}
 
 	fmt.Fprint(w, "\n</g>\n")
+	if f.badge != "" {
+		f.writeBadge(w, style, width, height)
+	}
 	fmt.Fprint(w, "</svg>\n")
 }
 
+func (f *Formatter) badgeWidth() int {
+	if f.badge == "" {
+		return 0
+	}
+	return 8 * (len([]rune(f.badge)) + 2)
+}
+
+// writeBadge writes the language badge into the corner of an image of the given size.
+func (f *Formatter) writeBadge(w io.Writer, style *chroma.Style, width, height int) {
+	bg := style.Get(chroma.Background)
+	colour, background := f.badgeColour, f.badgeBackground
+	if !colour.IsSet() {
+		colour = bg.Background
+		if !colour.IsSet() {
+			colour = chroma.MustParseColour("#ffffff")
+		}
+	}
+	if !background.IsSet() {
+		background = bg.Colour
+		if !background.IsSet() {
+			background = chroma.MustParseColour("#000000")
+		}
+	}
+	badgeWidth, badgeHeight := f.badgeWidth(), 18
+	x, y := width-badgeWidth, 0
+	switch f.badgeCorner {
+	case "top-left":
+		x = 0
+	case "bottom-right":
+		y = height - badgeHeight
+	case "bottom-left":
+		x, y = 0, height-badgeHeight
+	}
+	fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x, y, badgeWidth, badgeHeight, background)
+	fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" font-family=\"%s\" font-size=\"12px\" fill=\"%s\" xml:space=\"preserve\">%s</text>\n",
+		x+8, y+13, f.fontFamily, colour, escapeString(f.badge))
+}
+
 func maxLineWidth(lines [][]chroma.Token) int {
 	maxWidth := 0
 	for _, tokens := range lines {
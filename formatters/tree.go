@@ -0,0 +1,84 @@
+package formatters
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Tree formatter outputs a JSON tree of lines and their tokens, eg. for
+// rendering with a virtual DOM.
+var Tree = Register("tree", NewTree(nil, nil))
+
+// A Node in the tree output by the Tree formatter.
+//
+// The root node's children are lines, and the children of each line are its
+// tokens.
+type Node struct {
+	// Kind of node: "root", "line" or "token".
+	Kind string `json:"kind"`
+	// 1-based line number of line and token nodes.
+	Line int `json:"line,omitempty"`
+	// TokenType and value of token nodes.
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Resolved style of token nodes, in Pygments style entry syntax.
+	Style string `json:"style,omitempty"`
+	// Metadata of token nodes from a MetadataFunc, or of line nodes from a
+	// LineMetadataFunc.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Children []*Node           `json:"children,omitempty"`
+}
+
+// A MetadataFunc returns metadata to attach to the node of token, which starts
+// at byte offset in the source, eg. to key click handlers for go-to-definition.
+// It may return nil.
+type MetadataFunc func(token chroma.Token, offset int) map[string]string
+
+// A LineMetadataFunc returns metadata to attach to the node of the 1-based
+// line, eg. coverage hit counts to render in a gutter. It may return nil.
+type LineMetadataFunc func(line int) map[string]string
+
+// NewTree creates a formatter like Tree, attaching the metadata returned by
+// metadata and lineMetadata, if not nil, to each token and line node
+// respectively.
+func NewTree(metadata MetadataFunc, lineMetadata LineMetadataFunc) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		data, err := json.MarshalIndent(BuildTree(style, it.Tokens(), metadata, lineMetadata), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// BuildTree builds the tree of Nodes for tokens, as output by NewTree. style
+// may be nil, in which case token nodes are not styled.
+func BuildTree(style *chroma.Style, tokens []chroma.Token, metadata MetadataFunc, lineMetadata LineMetadataFunc) *Node {
+	root := &Node{Kind: "root"}
+	offset := 0
+	for i, line := range chroma.SplitTokensIntoLines(tokens) {
+		lineNode := &Node{Kind: "line", Line: i + 1}
+		if lineMetadata != nil {
+			lineNode.Metadata = lineMetadata(i + 1)
+		}
+		for _, token := range line {
+			if token.Value == "" {
+				continue
+			}
+			node := &Node{Kind: "token", Line: i + 1, Type: token.Type.String(), Value: token.Value}
+			if style != nil {
+				node.Style = style.Get(token.Type).String()
+			}
+			if metadata != nil {
+				node.Metadata = metadata(token, offset)
+			}
+			lineNode.Children = append(lineNode.Children, node)
+			offset += len(token.Value)
+		}
+		root.Children = append(root.Children, lineNode)
+	}
+	return root
+}
@@ -0,0 +1,84 @@
+package formatters
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestTree(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background: "#000000",
+		chroma.Keyword:    "bold #0000ff",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "var"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Name, Value: "x"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.Name, Value: "x"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	// Link identifiers to their definition.
+	definitions := map[string]int{"x": 4}
+	metadata := func(token chroma.Token, offset int) map[string]string {
+		if token.Type != chroma.Name {
+			return nil
+		}
+		return map[string]string{"offset": strconv.Itoa(offset), "definition": strconv.Itoa(definitions[token.Value])}
+	}
+	buf := &bytes.Buffer{}
+	err := NewTree(metadata, nil).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+
+	root := &Node{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), root))
+	assert.Equal(t, &Node{Kind: "root", Children: []*Node{
+		{Kind: "line", Line: 1, Children: []*Node{
+			{Kind: "token", Line: 1, Type: "Keyword", Value: "var", Style: "bold #0000ff"},
+			{Kind: "token", Line: 1, Type: "Text", Value: " ", Style: "#000000"},
+			{Kind: "token", Line: 1, Type: "Name", Value: "x", Style: "#000000", Metadata: map[string]string{"offset": "4", "definition": "4"}},
+			{Kind: "token", Line: 1, Type: "Text", Value: "\n", Style: "#000000"},
+		}},
+		{Kind: "line", Line: 2, Children: []*Node{
+			{Kind: "token", Line: 2, Type: "Name", Value: "x", Style: "#000000", Metadata: map[string]string{"offset": "6", "definition": "4"}},
+			{Kind: "token", Line: 2, Type: "Text", Value: "\n", Style: "#000000"},
+		}},
+	}}, root)
+	assert.Contains(t, buf.String(), `"metadata": {
+            "definition": "4",
+            "offset": "4"
+          }`)
+}
+
+func TestTreeLineMetadata(t *testing.T) {
+	tokens := []chroma.Token{
+		{Type: chroma.Name, Value: "a"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.Name, Value: "b"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	// Coverage hit counts, with line 2 not covered.
+	hits := map[int]int{1: 3}
+	coverage := func(line int) map[string]string {
+		if _, ok := hits[line]; !ok {
+			return nil
+		}
+		return map[string]string{"hits": strconv.Itoa(hits[line])}
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, NewTree(nil, coverage).Format(buf, nil, chroma.Literator(tokens...)))
+
+	root := &Node{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), root))
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, map[string]string{"hits": "3"}, root.Children[0].Metadata)
+	assert.Nil(t, root.Children[1].Metadata)
+	assert.Nil(t, root.Children[0].Children[0].Metadata)
+}
@@ -0,0 +1,71 @@
+package formatters
+
+import (
+	"io"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// TerminalCursor wraps a terminal formatter, rendering a blinking block cursor
+// over the character at the given byte offset in the source, eg. for tutorials
+// and screencasts. The token containing the offset is split so that the cursor
+// covers exactly one character. A cursor on a newline, or past the end of the
+// text, is rendered as a space. A negative offset disables the cursor.
+//
+// The cursor is drawn with the blink and reverse video attributes, around the
+// character as styled by the wrapped formatter. The wrapped formatter is
+// invoked separately for the text before the cursor, the cursor and the text
+// after it, so it must write nothing but its tokens, as the terminal formatters
+// do.
+func TerminalCursor(formatter chroma.Formatter, offset int) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		if offset < 0 {
+			return formatter.Format(w, style, it)
+		}
+		before, cursor, after := splitTokensAtCursor(it.Tokens(), offset)
+		if err := formatter.Format(w, style, chroma.Literator(before...)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\033[5m\033[7m"); err != nil {
+			return err
+		}
+		if err := formatter.Format(w, style, chroma.Literator(cursor)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\033[0m"); err != nil {
+			return err
+		}
+		return formatter.Format(w, style, chroma.Literator(after...))
+	})
+}
+
+// splitTokensAtCursor splits tokens into those before the character at the
+// byte offset, a token for the character itself, and those after it.
+func splitTokensAtCursor(tokens []chroma.Token, offset int) (before []chroma.Token, cursor chroma.Token, after []chroma.Token) {
+	pos := 0
+	for i, token := range tokens {
+		if offset >= pos+len(token.Value) {
+			pos += len(token.Value)
+			continue
+		}
+		rel := offset - pos
+		_, size := utf8.DecodeRuneInString(token.Value[rel:])
+		before = append(before, tokens[:i]...)
+		if rel > 0 {
+			before = append(before, chroma.Token{Type: token.Type, Value: token.Value[:rel]})
+		}
+		cursor = chroma.Token{Type: token.Type, Value: token.Value[rel : rel+size]}
+		if cursor.Value == "\n" {
+			// Keep the newline after the cursor.
+			cursor.Value = " "
+			size = 0
+		}
+		if rel+size < len(token.Value) {
+			after = append(after, chroma.Token{Type: token.Type, Value: token.Value[rel+size:]})
+		}
+		after = append(after, tokens[i+1:]...)
+		return before, cursor, after
+	}
+	return tokens, chroma.Token{Type: chroma.Text, Value: " "}, nil
+}
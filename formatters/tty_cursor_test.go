@@ -0,0 +1,36 @@
+package formatters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestTerminalCursor(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Keyword: "#ff0000",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "func"},
+		{Type: chroma.Text, Value: " main\n"},
+	}
+	format := func(offset int) string {
+		buf := &bytes.Buffer{}
+		err := TerminalCursor(TTY256, offset).Format(buf, style, chroma.Literator(tokens...))
+		require.NoError(t, err)
+		return buf.String()
+	}
+	red := "\033[38;5;196m"
+	// Mid-token.
+	assert.Equal(t, red+"fu\033[0m\033[5m\033[7m"+red+"n\033[0m\033[0m"+red+"c\033[0m main\n", format(2))
+	// On a newline.
+	assert.Equal(t, red+"func\033[0m main\033[5m\033[7m \033[0m\n", format(9))
+	// Past the end.
+	assert.Equal(t, red+"func\033[0m main\n\033[5m\033[7m \033[0m", format(100))
+	// Disabled.
+	assert.Equal(t, red+"func\033[0m main\n", format(-1))
+}
@@ -0,0 +1,57 @@
+package formatters
+
+import (
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// TerminalDefaults wraps a terminal formatter, omitting colours that match
+// the terminal's default foreground and background colours so that only
+// deviations from them are emitted, eg. to respect the user's terminal theme.
+// Tokens whose style matches the defaults entirely are written without any
+// escape sequences. Unset colours are never omitted.
+//
+// Tokens are retyped with synthesised types, so the wrapped formatter must
+// resolve styles by token type, as the terminal formatters do.
+func TerminalDefaults(formatter chroma.Formatter, foreground, background chroma.Colour) chroma.Formatter {
+	return chroma.FormatterFunc(func(w io.Writer, style *chroma.Style, it chroma.Iterator) error {
+		builder := chroma.NewStyleBuilder(style.Name)
+		types := map[chroma.StyleEntry]chroma.TokenType{}
+		var out []chroma.Token
+		for t := it(); t != chroma.EOF; t = it() {
+			entry := style.Get(t.Type)
+			if foreground.IsSet() && entry.Colour == foreground {
+				entry.Colour = 0
+			}
+			if background.IsSet() && entry.Background == background {
+				entry.Background = 0
+			}
+			entry.NoInherit = false
+			// Font attributes only produce escape sequences when enabled.
+			if entry.Bold != chroma.Yes && entry.Italic != chroma.Yes && entry.Underline != chroma.Yes {
+				entry.Bold, entry.Italic, entry.Underline = chroma.Pass, chroma.Pass, chroma.Pass
+			}
+			if entry.IsZero() {
+				// Unstyled in the derived style.
+				t.Type = chroma.Text
+				out = append(out, t)
+				continue
+			}
+			entry.NoInherit = true
+			tt, ok := types[entry]
+			if !ok {
+				tt = compositeTypeBase + chroma.TokenType(len(types))
+				types[entry] = tt
+				builder.AddEntry(tt, entry)
+			}
+			t.Type = tt
+			out = append(out, t)
+		}
+		derived, err := builder.Build()
+		if err != nil {
+			return err
+		}
+		return formatter.Format(w, derived, chroma.Literator(out...))
+	})
+}
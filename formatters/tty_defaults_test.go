@@ -0,0 +1,34 @@
+package formatters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestTerminalDefaults(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background: "#d0d0d0 bg:#1c1c1c",
+		chroma.Keyword:    "bold #d0d0d0",
+		chroma.String:     "#ff0000 bg:#1c1c1c",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Name, Value: "x"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Keyword, Value: "if"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.String, Value: `"y"`},
+	}
+	formatter := TerminalDefaults(TTY16m, chroma.MustParseColour("#d0d0d0"), chroma.MustParseColour("#1c1c1c"))
+	buf := &bytes.Buffer{}
+	require.NoError(t, formatter.Format(buf, style, chroma.Literator(tokens...)))
+	assert.Equal(t, "x \033[1mif\033[0m \033[38;2;255;0;0m\"y\"\033[0m", buf.String())
+
+	buf.Reset()
+	require.NoError(t, TTY16m.Format(buf, style, chroma.Literator(tokens[0])))
+	assert.Equal(t, "\033[38;2;208;208;208mx\033[0m", buf.String())
+}
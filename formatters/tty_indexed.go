@@ -239,6 +239,11 @@ type indexedTTYFormatter struct {
 }
 
 func (c *indexedTTYFormatter) Format(w io.Writer, style *chroma.Style, it chroma.Iterator) (err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			err = perr.(error)
+		}
+	}()
 	theme := styleToEscapeSequence(c.table, style)
 	for token := it(); token != chroma.EOF; token = it() {
 		clr, ok := theme[token.Type]
@@ -280,3 +285,35 @@ var TTY16 = Register("terminal16", &indexedTTYFormatter{ttyTables[16]})
 //
 // The Lab colour space is used to map RGB values to the most appropriate index colour.
 var TTY256 = Register("terminal256", &indexedTTYFormatter{ttyTables[256]})
+
+// NewTTYPalette creates an indexed terminal formatter for a terminal with a
+// custom palette, eg. a themed 16-colour terminal, where palette[i] is the
+// colour the terminal displays for colour index i. Style colours are mapped to
+// the nearest colour in the palette.
+//
+// Palettes of up to 16 colours use the 16-colour escape sequences, and larger
+// palettes the 256-colour ones. Colours beyond the 256th are ignored.
+func NewTTYPalette(palette []chroma.Colour) chroma.Formatter {
+	table := &ttyTable{foreground: map[chroma.Colour]string{}, background: map[chroma.Colour]string{}}
+	for i, colour := range palette {
+		if i >= 256 {
+			break
+		}
+		// Prefer the lowest index for duplicate colours.
+		if _, ok := table.foreground[colour]; ok {
+			continue
+		}
+		switch {
+		case len(palette) > 16:
+			table.foreground[colour] = fmt.Sprintf("\033[38;5;%dm", i)
+			table.background[colour] = fmt.Sprintf("\033[48;5;%dm", i)
+		case i < 8:
+			table.foreground[colour] = fmt.Sprintf("\033[%dm", 30+i)
+			table.background[colour] = fmt.Sprintf("\033[%dm", 40+i)
+		default:
+			table.foreground[colour] = fmt.Sprintf("\033[%dm", 90+i-8)
+			table.background[colour] = fmt.Sprintf("\033[%dm", 100+i-8)
+		}
+	}
+	return &indexedTTYFormatter{table}
+}
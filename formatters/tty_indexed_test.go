@@ -1,13 +1,48 @@
 package formatters
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClosestColour(t *testing.T) {
 	actual := findClosest(ttyTables[256], chroma.MustParseColour("#e06c75"))
 	assert.Equal(t, chroma.MustParseColour("#d75f87"), actual)
 }
+
+func TestTTYPalette(t *testing.T) {
+	// A themed terminal where "red" is pink and "blue" is teal.
+	palette := []chroma.Colour{
+		chroma.MustParseColour("#1d1f21"),
+		chroma.MustParseColour("#ff79c6"),
+		chroma.MustParseColour("#50fa7b"),
+		chroma.MustParseColour("#f1fa8c"),
+		chroma.MustParseColour("#008b8b"),
+	}
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Keyword: "#ff69b4",
+		chroma.Name:    "bg:#00a0a0",
+		chroma.String:  "#ffff00",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Keyword, Value: "if"},
+		{Type: chroma.Name, Value: "x"},
+		{Type: chroma.String, Value: `"y"`},
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, NewTTYPalette(palette).Format(buf, style, chroma.Literator(tokens...)))
+	assert.Equal(t, "\033[31mif\033[0m\033[44mx\033[0m\033[33m\"y\"\033[0m", buf.String())
+
+	palette = append(palette, make([]chroma.Colour, 20)...)
+	for i := range palette[5:] {
+		palette[5+i] = chroma.Colour(i + 1)
+	}
+	palette[24] = chroma.MustParseColour("#ff69b4")
+	buf.Reset()
+	require.NoError(t, NewTTYPalette(palette).Format(buf, style, chroma.Literator(tokens[0])))
+	assert.Equal(t, "\033[38;5;24mif\033[0m", buf.String())
+}
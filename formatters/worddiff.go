@@ -0,0 +1,116 @@
+package formatters
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// WordDiff computes a word-level diff of each pair of old and new lines, eg. the
+// removed and added lines of a diff hunk, returning providers for Composite that
+// decorate the words removed from the old lines with removed, and those
+// inserted into the new lines with inserted.
+//
+// The old provider decorates the text formed by the old line of each pair
+// followed by a newline, and likewise the new provider the new lines, so each
+// side is expected to be highlighted separately:
+//
+//	oldDiff, newDiff := WordDiff(pairs, removed, inserted)
+//	err := Composite(formatter, oldDiff).Format(w, style, oldTokens)
+func WordDiff(pairs [][2]string, removed, inserted chroma.StyleEntry) (oldDiff, newDiff DecorationProvider) {
+	var oldDecorations, newDecorations []Decoration
+	oldOffset, newOffset := 0, 0
+	for _, pair := range pairs {
+		oldRanges, newRanges := diffWords(pair[0], pair[1])
+		for _, r := range oldRanges {
+			oldDecorations = append(oldDecorations, Decoration{Start: oldOffset + r[0], End: oldOffset + r[1], Style: removed})
+		}
+		for _, r := range newRanges {
+			newDecorations = append(newDecorations, Decoration{Start: newOffset + r[0], End: newOffset + r[1], Style: inserted})
+		}
+		oldOffset += len(pair[0]) + 1
+		newOffset += len(pair[1]) + 1
+	}
+	oldDiff = func([]chroma.Token) []Decoration { return oldDecorations }
+	newDiff = func([]chroma.Token) []Decoration { return newDecorations }
+	return oldDiff, newDiff
+}
+
+// diffWords returns the [start, end) byte ranges of the words in a and b that
+// are not part of their longest common subsequence of words.
+func diffWords(a, b string) (removed, inserted [][2]int) {
+	aw, bw := splitWords(a), splitWords(b)
+	// lcs[i][j] is the length of the longest common subsequence of aw[i:] and bw[j:].
+	lcs := make([][]int, len(aw)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bw)+1)
+	}
+	for i := len(aw) - 1; i >= 0; i-- {
+		for j := len(bw) - 1; j >= 0; j-- {
+			switch {
+			case a[aw[i][0]:aw[i][1]] == b[bw[j][0]:bw[j][1]]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(aw) || j < len(bw) {
+		switch {
+		case i < len(aw) && j < len(bw) && a[aw[i][0]:aw[i][1]] == b[bw[j][0]:bw[j][1]]:
+			i++
+			j++
+		case j == len(bw) || (i < len(aw) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = appendRange(removed, aw[i])
+			i++
+		default:
+			inserted = appendRange(inserted, bw[j])
+			j++
+		}
+	}
+	return removed, inserted
+}
+
+// appendRange appends r to ranges, extending the last range if they are contiguous.
+func appendRange(ranges [][2]int, r [2]int) [][2]int {
+	if len(ranges) > 0 && ranges[len(ranges)-1][1] == r[0] {
+		ranges[len(ranges)-1][1] = r[1]
+		return ranges
+	}
+	return append(ranges, r)
+}
+
+// splitWords splits s into the byte ranges of its words: runs of letters,
+// digits and underscores, runs of whitespace, and individual other characters.
+func splitWords(s string) [][2]int {
+	class := func(r rune) int {
+		switch {
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		}
+		return 0
+	}
+	words := [][2]int{}
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		end := i + n
+		if c := class(r); c != 0 {
+			for end < len(s) {
+				next, n := utf8.DecodeRuneInString(s[end:])
+				if class(next) != c {
+					break
+				}
+				end += n
+			}
+		}
+		words = append(words, [2]int{i, end})
+		i = end
+	}
+	return words
+}
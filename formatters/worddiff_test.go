@@ -0,0 +1,55 @@
+package formatters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+)
+
+func TestWordDiff(t *testing.T) {
+	removed := chroma.StyleEntry{Background: chroma.MustParseColour("#ffcccc")}
+	inserted := chroma.StyleEntry{Background: chroma.MustParseColour("#ccffcc")}
+	oldDiff, newDiff := WordDiff([][2]string{
+		{"same line", "same line"},
+		{"x := foo(1)", "x := bar(1)"},
+	}, removed, inserted)
+	assert.Equal(t, []Decoration{{Start: 15, End: 18, Style: removed}}, oldDiff(nil))
+	assert.Equal(t, []Decoration{{Start: 15, End: 18, Style: inserted}}, newDiff(nil))
+
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background:   "#000000 bg:#ffffff",
+		chroma.NameFunction: "#0000ff",
+	})
+	tokens := []chroma.Token{
+		{Type: chroma.Text, Value: "same line\n"},
+		{Type: chroma.Name, Value: "x"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Operator, Value: ":="},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.NameFunction, Value: "bar"},
+		{Type: chroma.Punctuation, Value: "("},
+		{Type: chroma.LiteralNumber, Value: "1"},
+		{Type: chroma.Punctuation, Value: ")"},
+		{Type: chroma.Text, Value: "\n"},
+	}
+	buf := &bytes.Buffer{}
+	err := Composite(html.New(html.PreventSurroundingPre(true)), newDiff).Format(buf, style, chroma.Literator(tokens...))
+	require.NoError(t, err)
+	// The syntax colour is kept beneath the inserted background.
+	assert.Contains(t, buf.String(), `x := <span style="color:#00f;background-color:#cfc">bar</span>(1)`)
+}
+
+func TestDiffWords(t *testing.T) {
+	removed, inserted := diffWords("return a + b", "return a - c")
+	assert.Equal(t, [][2]int{{9, 10}, {11, 12}}, removed)
+	assert.Equal(t, [][2]int{{9, 10}, {11, 12}}, inserted)
+
+	removed, inserted = diffWords("f(a)", "f(a, b)")
+	assert.Empty(t, removed)
+	assert.Equal(t, [][2]int{{3, 6}}, inserted)
+}
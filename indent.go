@@ -0,0 +1,78 @@
+package chroma
+
+import (
+	"strings"
+)
+
+// IndentMode selects the leading whitespace produced by ConvertIndentation.
+type IndentMode int
+
+// Indentation modes.
+const (
+	// IndentSpaces indents with spaces only.
+	IndentSpaces IndentMode = iota
+	// IndentTabs indents with tabs, padded with spaces to columns between tab stops.
+	IndentTabs
+)
+
+// DetectIndentWidth returns the probable number of columns per indentation
+// level of text, being the most common increase in space indentation between
+// successive non-blank lines. If text is not indented with spaces, the width
+// of a tab, 8, is returned.
+func DetectIndentWidth(text string) int {
+	counts := map[int]int{}
+	previous := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indent, "\t") {
+			continue
+		}
+		if delta := len(indent) - previous; delta > 0 {
+			counts[delta]++
+		}
+		previous = len(indent)
+	}
+	width, best := 8, 0
+	for delta, count := range counts {
+		if count > best || (count == best && delta < width) {
+			width, best = delta, count
+		}
+	}
+	return width
+}
+
+// ConvertIndentation converts the leading whitespace of each line of text to
+// spaces or tabs, with tab stops every width columns. If width <= 0 it is
+// detected with DetectIndentWidth. Whitespace following the first
+// non-whitespace character of a line is left unchanged.
+//
+// This is intended to normalise text before it is tokenised. As leading
+// whitespace may change in length, byte offsets into the converted text, eg.
+// those given to MatchBracket or formatter cursors, no longer correspond to
+// the original text. Line numbers are unaffected.
+func ConvertIndentation(text string, mode IndentMode, width int) string {
+	if width <= 0 {
+		width = DetectIndentWidth(text)
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		rest := strings.TrimLeft(line, " \t")
+		col := 0
+		for _, c := range line[:len(line)-len(rest)] {
+			if c == '\t' {
+				col += width - col%width
+			} else {
+				col++
+			}
+		}
+		indent := strings.Repeat(" ", col)
+		if mode == IndentTabs {
+			indent = strings.Repeat("\t", col/width) + strings.Repeat(" ", col%width)
+		}
+		lines[i] = indent + rest
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,27 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectIndentWidth(t *testing.T) {
+	assert.Equal(t, 2, DetectIndentWidth("a:\n  b:\n    c: 1\n  d: 2\n"))
+	assert.Equal(t, 4, DetectIndentWidth("if a:\n    b()\n    if c:\n        d()\n"))
+	assert.Equal(t, 8, DetectIndentWidth("func f() {\n\treturn\n}\n"))
+	assert.Equal(t, 8, DetectIndentWidth("flat\n"))
+}
+
+func TestConvertIndentation(t *testing.T) {
+	spaces := "if a:\n    b = \"\t\"\n    if c:\n        d()\n\n"
+	tabs := "if a:\n\tb = \"\t\"\n\tif c:\n\t\td()\n\n"
+	// Whitespace after the indent, eg. within strings, is preserved.
+	assert.Equal(t, tabs, ConvertIndentation(spaces, IndentTabs, 0))
+	assert.Equal(t, spaces, ConvertIndentation(tabs, IndentSpaces, 4))
+
+	// Columns that do not fall on tab stops are padded with spaces.
+	assert.Equal(t, "\t  x\n", ConvertIndentation("      x\n", IndentTabs, 4))
+	// Mixed indentation is expanded to tab stops.
+	assert.Equal(t, "        x\n", ConvertIndentation("  \t    x\n", IndentSpaces, 4))
+}
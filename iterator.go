@@ -1,6 +1,11 @@
 package chroma
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
 
 // An Iterator across tokens.
 //
@@ -18,6 +23,13 @@ func (i Iterator) Tokens() []Token {
 	return out
 }
 
+// TokensWithEOF consumes all tokens from the iterator and returns them as a
+// slice terminated by the end-of-stream marker of policy, for consumers that
+// expect an explicit marker such as a typed empty token.
+func (i Iterator) TokensWithEOF(policy EOFPolicy) []Token {
+	return append(i.Tokens(), policy.New())
+}
+
 // Concaterator concatenates tokens from a series of iterators.
 func Concaterator(iterators ...Iterator) Iterator {
 	return func() Token {
@@ -44,6 +56,144 @@ func Literator(tokens ...Token) Iterator {
 	}
 }
 
+// A RunIterator across runs of consecutive tokens of the same TokenType.
+//
+// A nil slice of tokens is returned at the end of the Token stream.
+type RunIterator func() (TokenType, []Token)
+
+// GroupRuns groups consecutive tokens from it with the same TokenType into runs.
+//
+// Unlike Coalesce, token values are not merged.
+func GroupRuns(it Iterator) RunIterator {
+	var next *Token
+	return func() (TokenType, []Token) {
+		if next == nil {
+			t := it()
+			next = &t
+		}
+		if *next == EOF {
+			return EOFType, nil
+		}
+		run := []Token{*next}
+		for *next = it(); *next != EOF && next.Type == run[0].Type; *next = it() {
+			run = append(run, *next)
+		}
+		return run[0].Type, run
+	}
+}
+
+// FromExternal adapts tokens produced by another tool, eg. tree-sitter, into
+// an Iterator for use with Chroma's formatters.
+//
+// Tokens must cover source contiguously and without overlap, such that
+// concatenating their values reproduces it exactly. All tokens are validated
+// before any are returned, and an error is returned if a token is empty,
+// which would be indistinguishable from EOF, does not match the source at its
+// implied offset, indicating a gap or overlap, or is not valid UTF-8, which
+// usually indicates that a span was cut mid-character, or if the tokens end
+// before the end of source.
+//
+// The source is required, rather than just the tokens, as token values alone
+// can't reveal a gap or overlap. Validating up front, rather than while
+// iterating, means a formatter never writes partial output for an invalid
+// stream.
+func FromExternal(source string, tokens []Token) (Iterator, error) {
+	offset := 0
+	for i, t := range tokens {
+		if t.Value == "" {
+			return nil, fmt.Errorf("external token %d (%s) is empty", i, t.Type)
+		}
+		if !strings.HasPrefix(source[offset:], t.Value) {
+			return nil, fmt.Errorf("external token %d (%s) %q does not match the source at offset %d", i, t.Type, t.Value, offset)
+		}
+		if !utf8.ValidString(t.Value) {
+			return nil, fmt.Errorf("external token %d (%s) is not valid UTF-8: %q", i, t.Type, t.Value)
+		}
+		offset += len(t.Value)
+	}
+	if offset < len(source) {
+		return nil, fmt.Errorf("external tokens end at offset %d of %d", offset, len(source))
+	}
+	return Literator(tokens...), nil
+}
+
+// LimitBytes returns an Iterator that emits tokens from it until their combined
+// value length reaches maxBytes, splitting the final token if necessary.
+//
+// Tokens are only ever split on UTF-8 character boundaries, so the output may
+// be slightly shorter than maxBytes.
+func LimitBytes(it Iterator, maxBytes int) Iterator {
+	remaining := maxBytes
+	return func() Token {
+		if remaining <= 0 {
+			return EOF
+		}
+		t := it()
+		if len(t.Value) > remaining {
+			offset := remaining
+			for offset > 0 && !utf8.RuneStart(t.Value[offset]) {
+				offset--
+			}
+			remaining = offset
+			t, _ = splitToken(t, offset)
+		}
+		remaining -= len(t.Value)
+		return t
+	}
+}
+
+// TrimOption configures TrimTokens.
+type TrimOption func(*trimOptions)
+
+type trimOptions struct {
+	collapseEmptyLines bool
+}
+
+// CollapseTrailingEmptyLines additionally removes trailing empty lines, so that
+// the tokens end with at most one newline.
+func CollapseTrailingEmptyLines() TrimOption {
+	return func(o *trimOptions) { o.collapseEmptyLines = true }
+}
+
+// TrimTokens removes trailing empty and EOF tokens from tokens.
+func TrimTokens(tokens []Token, options ...TrimOption) []Token {
+	opts := &trimOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].Value == "" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if !opts.collapseEmptyLines {
+		return tokens
+	}
+	// Count the trailing newlines, which may span several tokens.
+	newlines := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		value := tokens[i].Value
+		trimmed := strings.TrimRight(value, "\n")
+		newlines += len(value) - len(trimmed)
+		if trimmed != "" {
+			break
+		}
+	}
+	// Remove all but one of them, dropping tokens left empty.
+	for excess := newlines - 1; excess > 0; {
+		last := tokens[len(tokens)-1]
+		n := len(last.Value) - len(strings.TrimRight(last.Value, "\n"))
+		if n > excess {
+			n = excess
+		}
+		excess -= n
+		last.Value = last.Value[:len(last.Value)-n]
+		tokens = tokens[: len(tokens)-1 : len(tokens)-1]
+		if last.Value != "" {
+			tokens = append(tokens, last)
+		}
+	}
+	return tokens
+}
+
 // SplitTokensIntoLines splits tokens containing newlines in two.
 func SplitTokensIntoLines(tokens []Token) (out [][]Token) {
 	var line []Token // nolint: prealloc
@@ -74,3 +224,119 @@ func SplitTokensIntoLines(tokens []Token) (out [][]Token) {
 	}
 	return
 }
+
+var paragraphBreakRe = regexp.MustCompile(`\n(?:[ \t]*\n)+`)
+
+// ParagraphBreaks retypes blank lines within Text tokens, ie. runs of two or
+// more newlines separated only by spaces or tabs, as TextParagraph. Runs may
+// lie within a larger Text token, as emitted by prose lexers, or span several
+// adjacent Text tokens. Token values are preserved exactly, with tokens split
+// at the boundaries of each run.
+func ParagraphBreaks(it Iterator) Iterator {
+	var (
+		text  []Token
+		queue []Token
+	)
+	return func() Token {
+		for len(queue) == 0 {
+			t := it()
+			if t != EOF && t.Type.InCategory(Text) && t.Value != "" {
+				text = append(text, t)
+				// A run cannot extend past non-whitespace, so the tokens so far can be split.
+				if last := t.Value[len(t.Value)-1]; last != ' ' && last != '\t' && last != '\n' {
+					queue = splitParagraphBreaks(text)
+					text = nil
+				}
+				continue
+			}
+			queue = append(splitParagraphBreaks(text), t)
+			text = nil
+		}
+		t := queue[0]
+		queue = queue[1:]
+		return t
+	}
+}
+
+func splitParagraphBreaks(tokens []Token) []Token {
+	text := Stringify(tokens...)
+	matches := paragraphBreakRe.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return tokens
+	}
+	out := make([]Token, 0, len(tokens)+len(matches)*2)
+	emit := func(tt TokenType, value string) {
+		if tt == TextParagraph && len(out) > 0 && out[len(out)-1].Type == TextParagraph {
+			out[len(out)-1].Value += value
+			return
+		}
+		out = append(out, Token{Type: tt, Value: value})
+	}
+	offset := 0
+	for _, t := range tokens {
+		pos, end := offset, offset+len(t.Value)
+		offset = end
+		for _, match := range matches {
+			if match[1] <= pos || match[0] >= end {
+				continue
+			}
+			if match[0] > pos {
+				emit(t.Type, text[pos:match[0]])
+				pos = match[0]
+			}
+			matchEnd := match[1]
+			if matchEnd > end {
+				matchEnd = end
+			}
+			emit(TextParagraph, text[pos:matchEnd])
+			pos = matchEnd
+		}
+		if pos < end {
+			emit(t.Type, text[pos:end])
+		}
+	}
+	return out
+}
+
+// LinesWithType returns the sorted 1-based numbers of the lines containing a
+// token of any of types, or of a sub-type of them, eg. Comment matches
+// CommentSingle. Lines are counted from the newlines embedded in token values,
+// with a newline belonging to the line it ends, so a multi-line token
+// contributes each line it spans.
+func LinesWithType(it Iterator, types ...TokenType) []int {
+	lines := []int{}
+	line := 1
+	mark := func() {
+		if len(lines) == 0 || lines[len(lines)-1] != line {
+			lines = append(lines, line)
+		}
+	}
+	for t := it(); t != EOF; t = it() {
+		match := false
+		for _, tt := range types {
+			if isTypeOrSubType(t.Type, tt) {
+				match = true
+				break
+			}
+		}
+		for i := 0; i < len(t.Value); i++ {
+			if match {
+				mark()
+			}
+			if t.Value[i] == '\n' {
+				line++
+			}
+		}
+	}
+	return lines
+}
+
+// isTypeOrSubType returns true if t is ancestor or descends from it.
+func isTypeOrSubType(t, ancestor TokenType) bool {
+	for ; t != 0; t = t.Parent() {
+		if t == ancestor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitBytes(t *testing.T) {
+	tokens := []Token{{Keyword, "func"}, {Whitespace, " "}, {NameFunction, "main"}}
+	assert.Equal(t, []Token{{Keyword, "func"}, {Whitespace, " "}},
+		LimitBytes(Literator(tokens...), 5).Tokens())
+	assert.Equal(t, []Token{{Keyword, "func"}, {Whitespace, " "}, {NameFunction, "ma"}},
+		LimitBytes(Literator(tokens...), 7).Tokens())
+	assert.Equal(t, tokens, LimitBytes(Literator(tokens...), 100).Tokens())
+	assert.Empty(t, LimitBytes(Literator(tokens...), 0).Tokens())
+	assert.Equal(t, []Token{{String, "a"}}, LimitBytes(Literator(Token{String, "aé"}), 2).Tokens())
+}
+
+func TestTrimTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []TrimOption
+		tokens   []Token
+		expected []Token
+	}{
+		{"Empty", nil, nil, nil},
+		{"NoTrailing", nil, []Token{{Keyword, "if"}}, []Token{{Keyword, "if"}}},
+		{"TrailingEmpty", nil, []Token{{Keyword, "if"}, {Text, ""}, EOF}, []Token{{Keyword, "if"}}},
+		{"KeepsEmptyLines", nil, []Token{{Keyword, "if"}, {Text, "\n\n"}}, []Token{{Keyword, "if"}, {Text, "\n\n"}}},
+		{"CollapseWithinToken", []TrimOption{CollapseTrailingEmptyLines()},
+			[]Token{{Keyword, "if"}, {Text, "\n\n\n"}, {Text, ""}},
+			[]Token{{Keyword, "if"}, {Text, "\n"}}},
+		{"CollapseAcrossTokens", []TrimOption{CollapseTrailingEmptyLines()},
+			[]Token{{Keyword, "if"}, {Text, "\n"}, {Text, "\n"}, {Whitespace, "\n"}},
+			[]Token{{Keyword, "if"}, {Text, "\n"}}},
+		{"CollapseIntoPrecedingToken", []TrimOption{CollapseTrailingEmptyLines()},
+			[]Token{{Keyword, "if"}, {Text, "x\n"}, {Text, "\n\n"}},
+			[]Token{{Keyword, "if"}, {Text, "x\n"}}},
+		{"CollapseSpanningTokens", []TrimOption{CollapseTrailingEmptyLines()},
+			[]Token{{Keyword, "if"}, {Text, "x\n\n"}, {Text, "\n"}},
+			[]Token{{Keyword, "if"}, {Text, "x\n"}}},
+		{"CollapseSingleNewline", []TrimOption{CollapseTrailingEmptyLines()},
+			[]Token{{Keyword, "if"}, {Text, "\n"}},
+			[]Token{{Keyword, "if"}, {Text, "\n"}}},
+	}
+	for _, test := range tests {
+		// nolint: scopelint
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, TrimTokens(test.tokens, test.options...))
+		})
+	}
+}
+
+func TestParagraphBreaks(t *testing.T) {
+	tokens := []Token{
+		{Text, "One"},
+		{Whitespace, "\n"},
+		{Text, "line"},
+		{Whitespace, " \n\n  "},
+		{Text, "Two"},
+		{Whitespace, "\n"},
+		{Text, " \n"},
+		{Whitespace, "\n"},
+		{Text, "Three\n"},
+	}
+	actual := ParagraphBreaks(Literator(tokens...)).Tokens()
+	assert.Equal(t, []Token{
+		{Text, "One"},
+		{Whitespace, "\n"},
+		{Text, "line"},
+		{Whitespace, " "},
+		{TextParagraph, "\n\n"},
+		{Whitespace, "  "},
+		{Text, "Two"},
+		{TextParagraph, "\n \n\n"},
+		{Text, "Three\n"},
+	}, actual)
+	assert.Equal(t, Stringify(tokens...), Stringify(actual...))
+}
+
+func TestGroupRuns(t *testing.T) {
+	tokens := []Token{
+		{Keyword, "a"}, {Keyword, "b"}, {Text, " "}, {Text, " "},
+		{Name, "c"}, {Keyword, "d"},
+	}
+	type run struct {
+		Type   TokenType
+		Tokens []Token
+	}
+	actual := []run{}
+	next := GroupRuns(Literator(tokens...))
+	for tt, group := next(); group != nil; tt, group = next() {
+		actual = append(actual, run{tt, group})
+	}
+	assert.Equal(t, []run{
+		{Keyword, []Token{{Keyword, "a"}, {Keyword, "b"}}},
+		{Text, []Token{{Text, " "}, {Text, " "}}},
+		{Name, []Token{{Name, "c"}}},
+		{Keyword, []Token{{Keyword, "d"}}},
+	}, actual)
+	tt, group := next()
+	assert.Equal(t, EOFType, tt)
+	assert.Nil(t, group)
+
+	tt, group = GroupRuns(Literator())()
+	assert.Equal(t, EOFType, tt)
+	assert.Nil(t, group)
+}
+
+func TestEOF(t *testing.T) {
+	empty := Token{Type: Text}
+	assert.True(t, EOF.IsEOF())
+	assert.True(t, (&Token{}).IsEOF())
+	assert.False(t, empty.IsEOF())
+	assert.False(t, (&Token{Value: "x"}).IsEOF())
+
+	// A typed empty token does not end the stream, but EOF does.
+	it := Literator(Token{Keyword, "a"}, empty, Token{Name, "b"}, EOF, Token{Name, "c"})
+	assert.Equal(t, []Token{{Keyword, "a"}, empty, {Name, "b"}}, it.Tokens())
+
+	policy := NewEOFPolicy(empty)
+	assert.Equal(t, empty, policy.New())
+	assert.True(t, policy.Is(empty))
+	assert.False(t, policy.Is(EOF))
+	assert.False(t, policy.Is(Token{Type: Text, Value: "x"}))
+
+	it = Literator(Token{Keyword, "a"})
+	tokens := it.TokensWithEOF(policy)
+	assert.Equal(t, []Token{{Keyword, "a"}, empty}, tokens)
+	assert.True(t, policy.Is(tokens[len(tokens)-1]))
+	assert.Equal(t, []Token{empty}, it.TokensWithEOF(policy))
+
+	// The zero policy uses EOF.
+	assert.Equal(t, []Token{EOF}, Literator().TokensWithEOF(EOFPolicy{}))
+	assert.True(t, EOFPolicy{}.Is(EOF))
+	assert.False(t, EOFPolicy{}.Is(empty))
+}
+
+func TestLinesWithType(t *testing.T) {
+	tokens := []Token{
+		{Keyword, "package"}, {Whitespace, " "}, {Name, "main"}, {Whitespace, "\n\n"},
+		{CommentSingle, "// TODO\n"},
+		{Keyword, "func"}, {Whitespace, " "}, {CommentMultiline, "/* a\nb */"}, {Whitespace, "\n"},
+		{Error, "?"}, {CommentSingle, "// x"}, {Whitespace, "\n"},
+	}
+	assert.Equal(t, []int{3, 4, 5, 6}, LinesWithType(Literator(tokens...), Comment))
+	assert.Equal(t, []int{4, 5}, LinesWithType(Literator(tokens...), CommentMultiline))
+	assert.Equal(t, []int{1, 4, 6}, LinesWithType(Literator(tokens...), Keyword, Error))
+	assert.Equal(t, []int{}, LinesWithType(Literator(tokens...), LiteralString))
+}
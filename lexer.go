@@ -57,6 +57,48 @@ type Config struct {
 	//
 	// If this is 0 it will be treated as a default of 1.
 	Priority float32 `xml:"priority,omitempty"`
+
+	// Analyse is a list of regexes used to score input in AnalyseText, if the
+	// Lexer has no analyser function.
+	Analyse *AnalyseConfig `xml:"analyse,omitempty"`
+
+	// Fold describes how source in the language folds into regions. See
+	// ComputeFoldRegions.
+	Fold *FoldConfig `xml:"fold,omitempty"`
+}
+
+// FoldConfig defines how a language folds.
+//
+// Regions are delimited by brackets unless Indent is true, in which case a
+// line starts a region spanning the more deeply indented lines that follow
+// it. Markers additionally delimit explicit regions.
+type FoldConfig struct {
+	Indent  bool               `xml:"indent,attr,omitempty"`
+	Markers []FoldMarkerConfig `xml:"marker,omitempty"`
+}
+
+// FoldMarkerConfig defines a pair of regexes matching the comments that start
+// and end a fold region, eg. "#region" and "#endregion".
+type FoldMarkerConfig struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// AnalyseConfig defines a list of regex analysis hints.
+//
+// The score is the sum of the scores of all matching patterns, capped at 1.0,
+// or if First is true the score of the first matching pattern.
+type AnalyseConfig struct {
+	Regexes []RegexConfig `xml:"regex,omitempty"`
+	First   bool          `xml:"first,attr,omitempty"`
+}
+
+// RegexConfig defines a single regex pattern and its score if it matches.
+//
+// Patterns are matched in multiline mode.
+type RegexConfig struct {
+	Pattern string  `xml:"pattern,attr"`
+	Score   float32 `xml:"score,attr"`
 }
 
 // Token output to formatter.
@@ -73,9 +115,44 @@ func (t *Token) Clone() Token {
 	return *t
 }
 
+// IsEOF returns true if t is the EOF marker.
+//
+// Only the zero Token is EOF; a token with a type but an empty value is not.
+func (t *Token) IsEOF() bool {
+	return *t == EOF
+}
+
 // EOF is returned by lexers at the end of input.
+//
+// EOF is the zero Token, so an Iterator must never return a token with neither
+// a type nor a value except to end the stream. Use IsEOF rather than testing
+// for an empty value.
 var EOF Token
 
+// An EOFPolicy determines how the end of a token stream is represented to
+// consumers collecting tokens, eg. with Iterator.TokensWithEOF.
+//
+// The zero EOFPolicy represents the end of the stream with EOF.
+type EOFPolicy struct {
+	marker Token
+}
+
+// NewEOFPolicy returns an EOFPolicy representing the end of a stream with
+// marker, eg. a typed empty token such as Token{Type: Text}.
+func NewEOFPolicy(marker Token) EOFPolicy {
+	return EOFPolicy{marker: marker}
+}
+
+// New returns a token marking the end of the stream.
+func (p EOFPolicy) New() Token {
+	return p.marker
+}
+
+// Is returns true if t marks the end of the stream under this policy.
+func (p EOFPolicy) Is(t Token) bool {
+	return t == p.marker
+}
+
 // TokeniseOptions contains options for tokenisers.
 type TokeniseOptions struct {
 	// State to start tokenisation in. Defaults to "root".
@@ -86,6 +163,52 @@ type TokeniseOptions struct {
 	// If true, all EOLs are converted into LF
 	// by replacing CRLF and CR
 	EnsureLF bool
+
+	// If true, the longest common leading whitespace of all non-blank lines is
+	// removed before lexing, as with Python's textwrap.dedent.
+	//
+	// Token values and offsets are then relative to the dedented text, and
+	// OriginalLenIterator does not account for the removed indentation.
+	Dedent bool
+
+	// If true, a trailing newline added to satisfy Config.EnsureNL is removed
+	// from the output, so the tokens reproduce input without a final newline
+	// exactly.
+	PreserveEOF bool
+
+	// If greater than 0, the maximum length in characters of a single rule
+	// match.
+	//
+	// A rule matching more than MaxMatchLength characters causes the Iterator
+	// to panic with ErrMatchTooLong, or if TruncateLongMatches is true, the
+	// first MaxMatchLength characters of the match are emitted as an Error
+	// token. Rules are always matched against the full input, so the rule
+	// selected is the one that would match without a limit; an overlong match
+	// is detected before its text is copied out of the input.
+	MaxMatchLength int
+	// Truncate matches longer than MaxMatchLength rather than failing.
+	//
+	// The rule's emitter and mutator are not applied to a truncated match, so
+	// the lexer state is unchanged and lexing resumes after the truncated
+	// prefix, in the middle of the construct, as if at its start.
+	TruncateLongMatches bool
+
+	// If greater than 0, lines longer than MaxLineLexLength characters,
+	// excluding the newline, are not lexed but emitted whole as a single Text
+	// token, eg. to avoid pathological rule matching on huge lines in log
+	// files. The lexer state is unchanged by a skipped line.
+	MaxLineLexLength int
+
+	// A rule matching the empty string without changing state would otherwise
+	// loop forever. By default the next character is instead emitted as an
+	// Error token and the match is reported to OnEmptyMatch, if set. If
+	// FailOnEmptyMatch is true the Iterator panics with ErrEmptyMatch.
+	FailOnEmptyMatch bool
+	OnEmptyMatch     func(err error)
+
+	// ExtraKeywords retypes Name tokens whose whole value is a key, eg. to
+	// highlight project specific terms as keywords without changing the lexer.
+	ExtraKeywords map[string]TokenType
 }
 
 // A Lexer for tokenising source code.
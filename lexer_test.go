@@ -46,3 +46,32 @@ func TestSimpleLexer(t *testing.T) {
 	}
 	require.Equal(t, expected, actual)
 }
+
+func TestAnalyseHints(t *testing.T) {
+	config := &Config{
+		Name: "Go",
+		Analyse: &AnalyseConfig{
+			Regexes: []RegexConfig{
+				{Pattern: `^package \w+$`, Score: 0.3},
+				{Pattern: `^func \w+\(`, Score: 0.3},
+				{Pattern: `:=`, Score: 0.5},
+			},
+		},
+	}
+	lexer := mustNewLexer(t, config, Rules{"root": {}}) // nolint: forbidigo
+	require.Equal(t, float32(0.0), lexer.AnalyseText("hello world"))
+	require.Equal(t, float32(0.6), lexer.AnalyseText("package main\n\nfunc main() {}\n"))
+	require.Equal(t, float32(1.0), lexer.AnalyseText("package main\n\nfunc main() { x := 1 }\n"))
+
+	config.Analyse.First = true
+	require.Equal(t, float32(0.5), lexer.AnalyseText("x := 1\n"))
+
+	data, err := Marshal(lexer)
+	require.NoError(t, err)
+	unmarshalled, err := Unmarshal(data)
+	require.NoError(t, err)
+	require.Equal(t, config.Analyse, unmarshalled.Config().Analyse)
+
+	_, err = NewLexer(&Config{Analyse: &AnalyseConfig{Regexes: []RegexConfig{{Pattern: `(`}}}}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package lexers
+
+import (
+	. "github.com/alecthomas/chroma/v2" // nolint
+)
+
+// DiffOf returns a lexer for unified diffs of source in the given language.
+//
+// The +/- prefixes and headers are highlighted as with the Diff lexer, while
+// the content of each context, inserted and deleted line is highlighted by
+// language. The content lines are lexed by language as a single stream, so
+// multi-line constructs spanning both inserted and deleted lines may be
+// highlighted incorrectly.
+func DiffOf(language Lexer) Lexer {
+	return DelegatingLexer(language, MustNewLexer(
+		&Config{
+			Name:     "Diff (" + language.Config().Name + ")",
+			EnsureNL: true,
+		},
+		diffOfRules,
+	))
+}
+
+func diffOfRules() Rules {
+	return Rules{
+		"root": {
+			{`\+\+\+ .*\n`, GenericInserted, nil},
+			{`--- .*\n`, GenericDeleted, nil},
+			{`( )(.*\n)`, ByGroups(Text, Other), nil},
+			{`(\+)(.*\n)`, ByGroups(GenericInserted, Other), nil},
+			{`(-)(.*\n)`, ByGroups(GenericDeleted, Other), nil},
+			{`!.*\n`, GenericStrong, nil},
+			{`@.*\n`, GenericSubheading, nil},
+			{`([Ii]ndex|diff).*\n`, GenericHeading, nil},
+			{`=.*\n`, GenericHeading, nil},
+			{`.*\n`, Text, nil},
+		},
+	}
+}
@@ -216,3 +216,15 @@ func Benchmark(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkAnalyse(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		lexers.Analyse(lexerBenchSource)
+	}
+}
+
+func BenchmarkAnalyseParallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		lexers.AnalyseParallel(lexerBenchSource, 0, 0)
+	}
+}
@@ -19,7 +19,7 @@ var GlobalLexerRegistry = func() *chroma.LexerRegistry {
 		panic(err)
 	}
 	for _, path := range paths {
-		reg.Register(chroma.MustNewXMLLexer(embedded, path))
+		reg.Register(chroma.MustNewLazyXMLLexer(embedded, path))
 	}
 	return reg
 }()
@@ -49,11 +49,37 @@ func Register(lexer chroma.Lexer) chroma.Lexer {
 	return GlobalLexerRegistry.Register(lexer)
 }
 
+// RegisterMagic associates a Lexer in the global registry with content that
+// begins with the given magic bytes.
+func RegisterMagic(magic string, lexer chroma.Lexer) {
+	GlobalLexerRegistry.RegisterMagic(magic, lexer)
+}
+
+// MatchMagic returns the Lexer whose magic signature prefixes text, or nil.
+func MatchMagic(text string) chroma.Lexer {
+	return GlobalLexerRegistry.MatchMagic(text)
+}
+
 // Analyse text content and return the "best" lexer..
 func Analyse(text string) chroma.Lexer {
 	return GlobalLexerRegistry.Analyse(text)
 }
 
+// DetectBest returns the best Lexer for a file, trying its filename, first
+// line and content in turn, along with the method that found it.
+//
+// See chroma.LexerRegistry.DetectBest.
+func DetectBest(filename string, content []byte) (chroma.Lexer, chroma.DetectionMethod) {
+	return GlobalLexerRegistry.DetectBest(filename, content)
+}
+
+// AnalyseParallel text content concurrently and return the "best" lexer.
+//
+// See chroma.LexerRegistry.AnalyseParallel.
+func AnalyseParallel(text string, workers int, threshold float32) chroma.Lexer {
+	return GlobalLexerRegistry.AnalyseParallel(text, workers, threshold)
+}
+
 // PlaintextRules is used for the fallback lexer as well as the explicit
 // plaintext lexer.
 func PlaintextRules() chroma.Rules {
@@ -194,3 +194,305 @@ func TestLexersTextAnalyser(t *testing.T) {
 		FileTestAnalysis(t, lexer, actualFilepath, expectedFilepath)
 	}
 }
+
+func TestAnalyseSassSyntax(t *testing.T) {
+	for _, test := range []struct {
+		file     string
+		expected string
+	}{
+		{"testdata/analysis/sass.indented.actual", "Sass"},
+		{"testdata/analysis/scss.braces.actual", "SCSS"},
+	} {
+		data, err := ioutil.ReadFile(test.file)
+		require.NoError(t, err)
+		lexer := lexers.Analyse(string(data))
+		require.NotNil(t, lexer)
+		require.Equal(t, test.expected, lexer.Config().Name)
+	}
+
+	sass, scss := lexers.AnalyseSassSyntax("def main():\n    print('hello')\n")
+	require.Zero(t, sass)
+	require.Zero(t, scss)
+}
+
+func TestMatchMagic(t *testing.T) {
+	source := "<?xml version=\"1.0\"?>\n<root><child attr=\"1\"/></root>\n"
+	assert.Equal(t, lexers.Get("xml"), lexers.MatchMagic(source))
+	assert.Equal(t, lexers.Get("xml"), lexers.Analyse(source))
+	assert.Equal(t, lexers.Get("xml"), lexers.MatchMagic("\uFEFF"+source))
+	assert.Nil(t, lexers.MatchMagic("plain text <?xml"))
+}
+
+func TestRegistryFallback(t *testing.T) {
+	registry := chroma.NewLexerRegistry()
+	registry.Register(chroma.MustNewLexer(&chroma.Config{Name: "Test", Filenames: []string{"*.test"}}, func() chroma.Rules {
+		return chroma.Rules{"root": {}}
+	}))
+	assert.Nil(t, registry.Get("unknown"))
+	assert.Nil(t, registry.Fallback())
+
+	registry.SetFallback(lexers.Fallback)
+	assert.Equal(t, lexers.Fallback, registry.Fallback())
+	assert.Equal(t, lexers.Fallback, registry.Get("unknown"))
+	assert.Equal(t, lexers.Fallback, registry.Match("file.unknown"))
+	assert.Equal(t, "Test", registry.Get("test").Config().Name)
+	assert.Equal(t, "Test", registry.Match("file.test").Config().Name)
+}
+
+func TestDiffOf(t *testing.T) {
+	lexer := chroma.Coalesce(lexers.DiffOf(lexers.Get("go")))
+	source := "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n func main() {\n-\tx := 1\n+\tx := \"one\"\n"
+	it, err := lexer.Tokenise(nil, source)
+	assert.NoError(t, err)
+	tokens := it.Tokens()
+	assert.Equal(t, source, chroma.Stringify(tokens...))
+	assert.Equal(t, []chroma.Token{
+		{Type: chroma.GenericDeleted, Value: "--- a/main.go\n"},
+		{Type: chroma.GenericInserted, Value: "+++ b/main.go\n"},
+		{Type: chroma.GenericSubheading, Value: "@@ -1,2 +1,2 @@\n"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.KeywordDeclaration, Value: "func"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.NameFunction, Value: "main"},
+		{Type: chroma.Punctuation, Value: "()"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Punctuation, Value: "{"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.GenericDeleted, Value: "-"},
+		{Type: chroma.Text, Value: "\t"},
+		{Type: chroma.NameOther, Value: "x"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Operator, Value: ":="},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.LiteralNumberInteger, Value: "1"},
+		{Type: chroma.Text, Value: "\n"},
+		{Type: chroma.GenericInserted, Value: "+"},
+		{Type: chroma.Text, Value: "\t"},
+		{Type: chroma.NameOther, Value: "x"},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.Operator, Value: ":="},
+		{Type: chroma.Text, Value: " "},
+		{Type: chroma.LiteralString, Value: "\"one\""},
+		{Type: chroma.Text, Value: "\n"},
+	}, tokens)
+}
+
+func TestCompileEmbedded(t *testing.T) {
+	lexer := lexers.Get("python").(*chroma.RegexLexer)
+	data, err := chroma.Compile(lexer)
+	require.NoError(t, err)
+	compiled, err := chroma.LoadCompiled(data)
+	require.NoError(t, err)
+	source := "def main():\n    print(f\"hello {name}\")  # greet\n"
+	expected, err := chroma.Tokenise(lexer, nil, source)
+	assert.NoError(t, err)
+	actual, err := chroma.Tokenise(compiled, nil, source)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestMapFilename(t *testing.T) {
+	registry := chroma.NewLexerRegistry()
+	for _, config := range []*chroma.Config{
+		{Name: "Groovy", Filenames: []string{"*.groovy"}},
+		{Name: "Makefile", Filenames: []string{"Makefile", "*.mk"}},
+	} {
+		registry.Register(chroma.MustNewLexer(config, func() chroma.Rules {
+			return chroma.Rules{"root": {}}
+		}))
+	}
+	assert.Nil(t, registry.Match("Jenkinsfile"))
+	registry.MapFilename("Jenkinsfile", "groovy")
+	registry.MapFilename("Makefile", "groovy")
+	assert.Equal(t, "Groovy", registry.Match("Jenkinsfile").Config().Name)
+	assert.Equal(t, "Groovy", registry.Match("path/to/Jenkinsfile").Config().Name)
+	assert.Equal(t, "Groovy", registry.Match("Makefile").Config().Name)
+	assert.Equal(t, "Makefile", registry.Match("rules.mk").Config().Name)
+}
+
+func TestMarkdownFenced(t *testing.T) {
+	lexer := lexers.MarkdownFenced(nil)
+	source := "# Title\n\n```go\nx := 1\n```\n\n~~~ {.python}\nimport os\n~~~\n\n```klingon\nqapla'\n```\n"
+	it, err := lexer.Tokenise(nil, source)
+	require.NoError(t, err)
+	tokens := it.Tokens()
+	assert.Equal(t, source, chroma.Stringify(tokens...))
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.Operator, Value: ":="})
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.KeywordNamespace, Value: "import"})
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.Text, Value: "qapla'\n"})
+	for _, token := range tokens {
+		assert.NotEqual(t, chroma.Error, token.Type, "%v", token)
+	}
+}
+
+func TestMarkdownFencedUnknown(t *testing.T) {
+	source := "```klingon\n#!/bin/sh\necho hi\n```\n"
+	tests := []struct {
+		policy   lexers.UnknownFencePolicy
+		expected chroma.Token
+	}{
+		{lexers.UnknownFencePlaintext, chroma.Token{Type: chroma.Text, Value: "#!/bin/sh\necho hi\n"}},
+		{lexers.UnknownFenceAnalyse, chroma.Token{Type: chroma.NameBuiltin, Value: "echo"}},
+		{lexers.UnknownFenceLexer(lexers.Get("python")), chroma.Token{Type: chroma.CommentHashbang, Value: "#!/bin/sh"}},
+	}
+	for _, test := range tests {
+		it, err := lexers.MarkdownFencedWith(nil, test.policy).Tokenise(nil, source)
+		require.NoError(t, err)
+		tokens := it.Tokens()
+		assert.Equal(t, source, chroma.Stringify(tokens...))
+		assert.Contains(t, tokens, test.expected)
+	}
+}
+
+func TestAnalyseParallel(t *testing.T) {
+	files, err := filepath.Glob("testdata/analysis/*.actual")
+	require.NoError(t, err)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		text := string(data)
+		assert.Equal(t, lexers.Analyse(text), lexers.AnalyseParallel(text, 4, 0), file)
+	}
+	assert.Equal(t, lexers.Get("xml"), lexers.AnalyseParallel("<?xml version=\"1.0\"?>\n", 4, 0))
+}
+
+func TestAnalyseHintsChangeWinner(t *testing.T) {
+	rules := func() chroma.Rules { return chroma.Rules{"root": {}} }
+	registry := chroma.NewLexerRegistry()
+	registry.Register(chroma.MustNewLexer(&chroma.Config{Name: "Generic"}, rules).SetAnalyser(func(text string) float32 {
+		return 0.2
+	}))
+	hinted := registry.Register(chroma.MustNewLexer(&chroma.Config{
+		Name: "Go",
+		Analyse: &chroma.AnalyseConfig{
+			Regexes: []chroma.RegexConfig{{Pattern: `^package main$`, Score: 0.5}},
+		},
+	}, rules))
+	assert.Equal(t, "Generic", registry.Analyse("hello\n").Config().Name)
+	assert.Equal(t, hinted, registry.Analyse("// Comment\npackage main\n"))
+}
+
+func TestUsingIf(t *testing.T) {
+	lexer := chroma.MustNewLexer(&chroma.Config{Name: "GoWithSQL"}, func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{`(")((?:\\.|[^"\\])*)(")`, chroma.ByGroups(
+					chroma.LiteralString,
+					chroma.UsingIf("SQL", `(?i)^\s*(select|insert|update|delete)\b`, chroma.LiteralString),
+					chroma.LiteralString,
+				), nil},
+				{`[^"]+`, chroma.Using("Go"), nil},
+			},
+		}
+	})
+	lexer.SetRegistry(lexers.GlobalLexerRegistry)
+	source := `db.Query("SELECT name FROM users WHERE id = ?", id, "hello")`
+	tokens, err := chroma.Tokenise(lexer, nil, source)
+	require.NoError(t, err)
+	assert.Equal(t, source, chroma.Stringify(tokens...))
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.Keyword, Value: "SELECT"})
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.Keyword, Value: "WHERE"})
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.LiteralString, Value: "hello"})
+	assert.Contains(t, tokens, chroma.Token{Type: chroma.NameFunction, Value: "Query"})
+
+	// Serialisable.
+	data, err := chroma.Marshal(lexer)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<usingif lexer="SQL"`)
+	unmarshalled, err := chroma.Unmarshal(data)
+	require.NoError(t, err)
+	unmarshalled.SetRegistry(lexers.GlobalLexerRegistry)
+	actual, err := chroma.Tokenise(unmarshalled, nil, source)
+	require.NoError(t, err)
+	assert.Equal(t, tokens, actual)
+}
+
+func TestUsingIfInvalidPattern(t *testing.T) {
+	lexer := chroma.MustNewLexer(&chroma.Config{Name: "Invalid"}, func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{`(")([^"]*)(")`, chroma.ByGroups(
+					chroma.LiteralString,
+					chroma.UsingIf("SQL", `(unclosed`, chroma.LiteralString),
+					chroma.LiteralString,
+				), nil},
+			},
+		}
+	})
+	_, err := chroma.Tokenise(lexer, nil, `"select 1"`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "(unclosed")
+
+	_, err = chroma.Unmarshal([]byte(`<lexer><config><name>Invalid</name></config><rules><state name="root">` +
+		`<rule pattern="(&quot;)([^&quot;]*)(&quot;)"><bygroups><token type="LiteralString"/>` +
+		`<usingif lexer="SQL" pattern="(unclosed"><emitters><token type="LiteralString"/></emitters></usingif>` +
+		`<token type="LiteralString"/></bygroups></rule></state></rules></lexer>`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "(unclosed")
+}
+
+func TestFoldRegions(t *testing.T) {
+	lexer := lexers.Get("csharp")
+	require.NotNil(t, lexer)
+	source := "class A {\n" +
+		"#region Fields\n" +
+		"  int a;\n" +
+		"  int b;\n" +
+		"#endregion Fields\n" +
+		"}\n"
+	it, err := lexer.Tokenise(nil, source)
+	require.NoError(t, err)
+	regions := chroma.ComputeFoldRegions(lexer.Config(), it.Tokens())
+	assert.Equal(t, []chroma.FoldRegion{{StartLine: 1, EndLine: 6}, {StartLine: 2, EndLine: 5}}, regions)
+}
+
+func TestDetectBest(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+		lexer    string
+		method   chroma.DetectionMethod
+	}{
+		{"main.go", "#!/usr/bin/env python3\n", "Go", chroma.DetectedFilename},
+		{"script", "#!/usr/bin/env python3\nimport os\n", "Python", chroma.DetectedFirstLine},
+		{"", "#!/usr/local/bin/python3.11 -u\n", "Python", chroma.DetectedFirstLine},
+		{"unknown.zzz", "#!/bin/bash\necho hi\n", "Bash", chroma.DetectedFirstLine},
+		{"", "<?xml version=\"1.0\"?>\n<a/>\n", "XML", chroma.DetectedFirstLine},
+		{"", "package main\n\nfunc main() { fmt.Println() }\n", "Go", chroma.DetectedAnalysis},
+	}
+	for _, test := range tests {
+		lexer, method := lexers.DetectBest(test.filename, []byte(test.content))
+		require.NotNil(t, lexer, test.content)
+		assert.Equal(t, test.lexer, lexer.Config().Name, test.content)
+		assert.Equal(t, test.method, method, test.content)
+	}
+
+	registry := chroma.NewLexerRegistry()
+	registry.SetFallback(lexers.Fallback)
+	lexer, method := registry.DetectBest("", []byte("nothing to see"))
+	assert.Equal(t, lexers.Fallback, lexer)
+	assert.Equal(t, chroma.DetectedNone, method)
+	assert.Equal(t, "none", method.String())
+}
+
+func TestPHTMLEndingWithPHP(t *testing.T) {
+	source := "<p>hi</p>\n<?php echo 1; ?>"
+	it, err := lexers.Get("phtml").Tokenise(nil, source)
+	require.NoError(t, err)
+	tokens := it.Tokens()
+	assert.Equal(t, source, chroma.Stringify(tokens...))
+	assert.Equal(t, chroma.Token{Type: chroma.CommentPreproc, Value: "?>"}, tokens[len(tokens)-1])
+}
+
+func TestParagraphBreaksProse(t *testing.T) {
+	source := "First para.\n\nSecond para.\n"
+	for _, name := range []string{"markdown", "rst", "plaintext"} {
+		t.Run(name, func(t *testing.T) {
+			it, err := lexers.Get(name).Tokenise(nil, source)
+			require.NoError(t, err)
+			tokens := chroma.ParagraphBreaks(it).Tokens()
+			assert.Equal(t, source, chroma.Stringify(tokens...))
+			assert.Contains(t, tokens, chroma.Token{Type: chroma.TextParagraph, Value: "\n\n"})
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package lexers
+
+// Well known content signatures.
+func init() { // nolint: gochecknoinits
+	for magic, name := range map[string]string{
+		"<?xml":          "XML",
+		"<?php":          "PHTML",
+		"<!DOCTYPE html": "HTML",
+		"<!doctype html": "HTML",
+		"%YAML":          "YAML",
+		"%!PS":           "PostScript",
+		"diff --git ":    "Diff",
+	} {
+		RegisterMagic(magic, Get(name))
+	}
+}
@@ -1,6 +1,8 @@
 package lexers
 
 import (
+	"strings"
+
 	. "github.com/alecthomas/chroma/v2" // nolint
 )
 
@@ -15,6 +17,84 @@ var Markdown = Register(DelegatingLexer(HTML, MustNewLexer(
 	markdownRules,
 )))
 
+// An UnknownFencePolicy returns the lexer for the code of a fenced block with
+// no info string or an unknown language.
+type UnknownFencePolicy func(registry *LexerRegistry, code string) Lexer
+
+// UnknownFencePlaintext highlights unknown fenced blocks as plain text.
+func UnknownFencePlaintext(registry *LexerRegistry, code string) Lexer { return Plaintext }
+
+// UnknownFenceAnalyse highlights unknown fenced blocks with the lexer that
+// registry.Analyse guesses for their code, or as plain text if none matches.
+func UnknownFenceAnalyse(registry *LexerRegistry, code string) Lexer {
+	if lexer := registry.Analyse(code); lexer != nil {
+		return lexer
+	}
+	return Plaintext
+}
+
+// UnknownFenceLexer highlights unknown fenced blocks with lexer.
+func UnknownFenceLexer(lexer Lexer) UnknownFencePolicy {
+	return func(*LexerRegistry, string) Lexer { return lexer }
+}
+
+// MarkdownFenced returns a Markdown lexer that highlights each ``` or ~~~
+// fenced code block with the lexer for its info string, resolved from
+// registry. Blocks with no info string or an unknown language are
+// highlighted as plain text.
+//
+// If registry is nil, GlobalLexerRegistry is used.
+func MarkdownFenced(registry *LexerRegistry) Lexer {
+	return MarkdownFencedWith(registry, UnknownFencePlaintext)
+}
+
+// MarkdownFencedWith is like MarkdownFenced, but resolves the lexer for blocks
+// with no info string or an unknown language with unknown.
+func MarkdownFencedWith(registry *LexerRegistry, unknown UnknownFencePolicy) Lexer {
+	if registry == nil {
+		registry = GlobalLexerRegistry
+	}
+	return DelegatingLexer(HTML, MustNewLexer(
+		&Config{
+			Name:      "markdown",
+			Aliases:   []string{"md", "mkd"},
+			Filenames: []string{"*.md", "*.mkd", "*.markdown"},
+			MimeTypes: []string{"text/x-markdown"},
+		},
+		func() Rules {
+			rules := markdownRules()
+			rules["root"] = append([]Rule{
+				{"^(`{3,}|~{3,})([^\\n`]*)(\\n)([\\w\\W]*?)(^\\1$)", fencedCodeEmitter(registry, unknown), nil},
+			}, rules["root"]...)
+			return rules
+		},
+	))
+}
+
+// fencedCodeEmitter emits a fenced code block, resolving its language from the
+// info string in groups[2].
+func fencedCodeEmitter(registry *LexerRegistry, unknown UnknownFencePolicy) Emitter {
+	return EmitterFunc(func(groups []string, state *LexerState) Iterator {
+		var lexer Lexer
+		if fields := strings.Fields(groups[2]); len(fields) > 0 {
+			// Support Pandoc style {.lang} info strings.
+			lexer = registry.Get(strings.TrimPrefix(strings.Trim(fields[0], "{}"), "."))
+		}
+		if lexer == nil {
+			lexer = unknown(registry, groups[4])
+		}
+		it, err := lexer.Tokenise(nil, groups[4])
+		if err != nil {
+			panic(err)
+		}
+		return Concaterator(
+			Literator(Token{Type: String, Value: groups[1]}, Token{Type: String, Value: groups[2]}, Token{Type: String, Value: groups[3]}),
+			it,
+			Literator(Token{Type: String, Value: groups[5]}),
+		)
+	})
+}
+
 func markdownRules() Rules {
 	return Rules{
 		"root": {
@@ -53,6 +53,7 @@ func restructuredtextRules() Rules {
 			{`<.+?>`, NameTag, nil},
 			{"[^\\\\\\n\\[*`:]+", Text, nil},
 			{`.`, Text, nil},
+			{`\n`, Text, nil},
 		},
 		"literal": {
 			{"[^`]+", LiteralString, nil},
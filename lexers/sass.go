@@ -0,0 +1,60 @@
+package lexers
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	stylesheetDeclarationRe = regexp.MustCompile(`(?m)^\s+[a-z-]+\s*:\s*\S`)
+	sassFeatureRe           = regexp.MustCompile(`(?m)^\s*(?:\$[\w-]+\s*:|@(?:mixin|include|extend|use|forward|function|each|if)\b|[=+][\w-]+|&)`)
+	braceSyntaxRe           = regexp.MustCompile(`(?m)[{}]|;\s*$`)
+)
+
+// AnalyseSassSyntax scores how likely text is to be a stylesheet written in the
+// indentation-based Sass syntax or the brace-based SCSS syntax, between 0.0 and
+// 1.0. Text that does not look like a Sass stylesheet, ie. that lacks either
+// indented declarations or Sass specific features such as variables, mixins
+// or parent selectors, scores 0 for both.
+//
+// The two syntaxes share most of their grammar, so the scores are primarily
+// decided by whether braces and trailing semicolons are present.
+func AnalyseSassSyntax(text string) (sass, scss float32) {
+	if !stylesheetDeclarationRe.MatchString(text) || !sassFeatureRe.MatchString(text) {
+		return 0, 0
+	}
+	const confidence = 0.3
+	if braceSyntaxRe.MatchString(text) {
+		return 0, confidence
+	}
+	// Indented syntax nests by indentation alone, so require at least one
+	// selector line immediately followed by a more deeply indented line.
+	lines := strings.Split(text, "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if indentation(lines[i+1]) > indentation(lines[i]) {
+			return confidence, 0
+		}
+	}
+	return 0, 0
+}
+
+func indentation(line string) int {
+	if strings.TrimSpace(line) == "" {
+		return 0
+	}
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func init() { // nolint: gochecknoinits
+	Get("sass").SetAnalyser(func(text string) float32 {
+		sass, _ := AnalyseSassSyntax(text)
+		return sass
+	})
+	Get("scss").SetAnalyser(func(text string) float32 {
+		_, scss := AnalyseSassSyntax(text)
+		return scss
+	})
+}
@@ -0,0 +1,67 @@
+package chroma
+
+import "regexp"
+
+var linkRe = regexp.MustCompile(`(?:https?|ftp)://[^\s<>"'` + "`" + `]*[^\s<>"'` + "`" + `.,;:!?)\]}]|\bmailto:[\w.+-]+@[\w-]+(?:\.[\w-]+)+|\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)
+
+// LinkifyComments splits Comment and String tokens around any URLs or email
+// addresses they contain.
+//
+// The links themselves are retyped as CommentLink or StringLink respectively,
+// so that formatters may render them as clickable. Other tokens are passed
+// through unchanged.
+func LinkifyComments(it Iterator) Iterator {
+	var queue []Token
+	return func() Token {
+		for len(queue) == 0 {
+			t := it()
+			if t == EOF {
+				return EOF
+			}
+			queue = linkify(t)
+		}
+		t := queue[0]
+		queue = queue[1:]
+		return t
+	}
+}
+
+func linkify(t Token) []Token {
+	var linkType TokenType
+	switch {
+	case t.Type == CommentLink || t.Type == StringLink:
+		return []Token{t}
+	case t.Type.InSubCategory(Comment):
+		linkType = CommentLink
+	case t.Type.InSubCategory(String):
+		linkType = StringLink
+	default:
+		return []Token{t}
+	}
+	return splitMatches(t, linkRe.FindAllStringIndex(t.Value, -1), linkType)
+}
+
+// splitMatches splits t around the non-overlapping byte ranges in matches, as
+// returned by regexp.FindAllStringIndex, retyping the matched ranges as typ.
+func splitMatches(t Token, matches [][]int, typ TokenType) []Token {
+	if matches == nil {
+		return []Token{t}
+	}
+	out := make([]Token, 0, len(matches)*2+1)
+	offset := 0
+	for _, match := range matches {
+		var head, matched Token
+		head, t = splitToken(t, match[0]-offset)
+		if head != EOF {
+			out = append(out, head)
+		}
+		matched, t = splitToken(t, match[1]-match[0])
+		matched.Type = typ
+		out = append(out, matched)
+		offset = match[1]
+	}
+	if t != EOF {
+		out = append(out, t)
+	}
+	return out
+}
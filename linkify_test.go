@@ -0,0 +1,29 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkifyComments(t *testing.T) {
+	it := LinkifyComments(Literator(
+		Token{CommentSingle, "// See https://example.com/docs?q=1, or mail bob@example.org."},
+		Token{Keyword, "http://not.a.comment"},
+		Token{StringDouble, `"https://example.com"`},
+		Token{CommentSingle, "// nothing here"},
+	))
+	expected := []Token{
+		{CommentSingle, "// See "},
+		{CommentLink, "https://example.com/docs?q=1"},
+		{CommentSingle, ", or mail "},
+		{CommentLink, "bob@example.org"},
+		{CommentSingle, "."},
+		{Keyword, "http://not.a.comment"},
+		{StringDouble, `"`},
+		{StringLink, "https://example.com"},
+		{StringDouble, `"`},
+		{CommentSingle, "// nothing here"},
+	}
+	assert.Equal(t, expected, it.Tokens())
+}
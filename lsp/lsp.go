@@ -0,0 +1,122 @@
+// Package lsp maps Chroma tokens to Language Server Protocol semantic tokens.
+//
+// See https://microsoft.github.io/language-server-protocol/specifications/specification-current/#textDocument_semanticTokens
+package lsp
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// TokenTypes is the semantic token type legend, in the order used for encoding.
+var TokenTypes = []string{
+	"namespace", "type", "class", "enum", "interface", "struct", "typeParameter", "parameter",
+	"variable", "property", "enumMember", "event", "function", "method", "macro", "keyword",
+	"modifier", "comment", "string", "number", "regexp", "operator", "decorator",
+}
+
+// TokenModifiers is the semantic token modifier legend, in the order used for encoding.
+var TokenModifiers = []string{
+	"declaration", "definition", "readonly", "static", "deprecated", "abstract", "async",
+	"modification", "documentation", "defaultLibrary",
+}
+
+type semanticType struct {
+	tokenType string
+	modifiers []string
+}
+
+var mapping = map[chroma.TokenType]semanticType{
+	chroma.Keyword:            {"keyword", nil},
+	chroma.KeywordType:        {"type", nil},
+	chroma.Name:               {"variable", nil},
+	chroma.NameAttribute:      {"property", nil},
+	chroma.NameBuiltin:        {"function", []string{"defaultLibrary"}},
+	chroma.NameBuiltinPseudo:  {"variable", []string{"defaultLibrary"}},
+	chroma.NameClass:          {"class", nil},
+	chroma.NameConstant:       {"variable", []string{"readonly"}},
+	chroma.NameDecorator:      {"decorator", nil},
+	chroma.NameEntity:         {"enumMember", nil},
+	chroma.NameException:      {"class", nil},
+	chroma.NameFunction:       {"function", nil},
+	chroma.NameFunctionMagic:  {"function", nil},
+	chroma.NameLabel:          {"macro", nil},
+	chroma.NameNamespace:      {"namespace", nil},
+	chroma.NameOperator:       {"operator", nil},
+	chroma.NameProperty:       {"property", nil},
+	chroma.NameTag:            {"type", nil},
+	chroma.NameVariableGlobal: {"variable", []string{"static"}},
+	chroma.Literal:            {"string", nil},
+	chroma.LiteralString:      {"string", nil},
+	chroma.LiteralStringDoc:   {"string", []string{"documentation"}},
+	chroma.LiteralStringRegex: {"regexp", nil},
+	chroma.LiteralNumber:      {"number", nil},
+	chroma.Operator:           {"operator", nil},
+	chroma.OperatorWord:       {"keyword", nil},
+	chroma.Comment:            {"comment", nil},
+	chroma.CommentSpecial:     {"comment", []string{"documentation"}},
+	chroma.CommentPreproc:     {"macro", nil},
+}
+
+// Map returns the LSP semantic token type and modifiers for a Chroma TokenType.
+//
+// Sub-categories and categories are consulted if there is no exact match. ok
+// is false if the type has no semantic equivalent, such as whitespace or
+// punctuation.
+func Map(tt chroma.TokenType) (tokenType string, modifiers []string, ok bool) {
+	for _, candidate := range []chroma.TokenType{tt, tt.SubCategory(), tt.Category()} {
+		if st, ok := mapping[candidate]; ok {
+			return st.tokenType, st.modifiers, true
+		}
+	}
+	return "", nil, false
+}
+
+// Encode tokens from an Iterator into the LSP relative semantic tokens format.
+//
+// Each token is encoded as five integers: line delta, start character delta,
+// length, token type index into TokenTypes, and a bitset of TokenModifiers.
+// Positions are computed from the token values and measured in UTF-16 code
+// units, as required by the protocol. Tokens spanning multiple lines are split
+// into one semantic token per line.
+func Encode(it chroma.Iterator) []uint32 {
+	typeIndex := map[string]uint32{}
+	for i, name := range TokenTypes {
+		typeIndex[name] = uint32(i)
+	}
+	modifierBit := map[string]uint32{}
+	for i, name := range TokenModifiers {
+		modifierBit[name] = 1 << uint(i)
+	}
+
+	out := []uint32{}
+	line, char := 0, 0
+	prevLine, prevChar := 0, 0
+	for t := it(); t != chroma.EOF; t = it() {
+		tokenType, modifiers, ok := Map(t.Type)
+		var bits uint32
+		for _, modifier := range modifiers {
+			bits |= modifierBit[modifier]
+		}
+		for i, part := range strings.SplitAfter(t.Value, "\n") {
+			if i > 0 {
+				line++
+				char = 0
+			}
+			text := strings.TrimSuffix(part, "\n")
+			length := len(utf16.Encode([]rune(text)))
+			if ok && length > 0 {
+				deltaChar := char
+				if line == prevLine {
+					deltaChar = char - prevChar
+				}
+				out = append(out, uint32(line-prevLine), uint32(deltaChar), uint32(length), typeIndex[tokenType], bits)
+				prevLine, prevChar = line, char
+			}
+			char += length
+		}
+	}
+	return out
+}
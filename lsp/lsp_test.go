@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+func TestMap(t *testing.T) {
+	tokenType, modifiers, ok := Map(chroma.NameBuiltin)
+	require.True(t, ok)
+	require.Equal(t, "function", tokenType)
+	require.Equal(t, []string{"defaultLibrary"}, modifiers)
+
+	tokenType, _, ok = Map(chroma.LiteralStringDouble)
+	require.True(t, ok)
+	require.Equal(t, "string", tokenType)
+
+	_, _, ok = Map(chroma.Punctuation)
+	require.False(t, ok)
+}
+
+func TestEncode(t *testing.T) {
+	it, err := lexers.Get("go").Tokenise(nil, "package main\n\n// é\nfunc main() { println(\"hi\") }\n")
+	require.NoError(t, err)
+	expected := []uint32{
+		0, 0, 7, 15, 0, // package
+		0, 8, 4, 8, 0, // main
+		2, 0, 4, 17, 0, // // é
+		1, 0, 4, 15, 0, // func
+		0, 5, 4, 12, 0, // main
+		0, 9, 7, 12, 512, // println
+		0, 8, 4, 18, 0, // "hi"
+	}
+	require.Equal(t, expected, Encode(it))
+}
@@ -0,0 +1,86 @@
+package chroma
+
+import (
+	"sort"
+)
+
+// A TokenOverride retypes the [Start, End) byte range of the source as Type.
+type TokenOverride struct {
+	Start, End int
+	Type       TokenType
+}
+
+// OverrideTokens applies overrides to the tokens of it, eg. to mark unused
+// variables found by a separate analysis. Tokens are split at override
+// boundaries. Where overrides overlap, the last one wins.
+func OverrideTokens(it Iterator, overrides []TokenOverride) Iterator {
+	type indexed struct {
+		TokenOverride
+		index int
+	}
+	sorted := make([]indexed, 0, len(overrides))
+	for i, o := range overrides {
+		if o.Start < o.End {
+			sorted = append(sorted, indexed{o, i})
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	offset := 0
+	var queue []Token
+	return func() Token {
+		for len(queue) == 0 {
+			t := it()
+			if t == EOF {
+				return EOF
+			}
+			start, end := offset, offset+len(t.Value)
+			offset = end
+			// Collect the overrides covering this token and their boundaries within it.
+			var covering []indexed
+			bounds := []int{start, end}
+			for _, o := range sorted {
+				if o.Start >= end {
+					break
+				}
+				if o.End <= start {
+					continue
+				}
+				covering = append(covering, o)
+				if o.Start > start {
+					bounds = append(bounds, o.Start)
+				}
+				if o.End < end {
+					bounds = append(bounds, o.End)
+				}
+			}
+			if len(covering) == 0 {
+				queue = append(queue, t)
+				continue
+			}
+			sort.Ints(bounds)
+			for i := 0; i < len(bounds)-1; i++ {
+				from, to := bounds[i], bounds[i+1]
+				if from == to {
+					continue
+				}
+				segment := t.Clone()
+				segment.Value = t.Value[from-start : to-start]
+				index := -1
+				for _, o := range covering {
+					if o.Start <= from && o.End >= to && o.index > index {
+						segment.Type, index = o.Type, o.index
+					}
+				}
+				// Merge adjacent segments of the same type.
+				if n := len(queue); n > 0 && queue[n-1].Type == segment.Type {
+					queue[n-1].Value += segment.Value
+					continue
+				}
+				queue = append(queue, segment)
+			}
+		}
+		t := queue[0]
+		queue = queue[1:]
+		return t
+	}
+}
@@ -0,0 +1,38 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverrideTokens(t *testing.T) {
+	tokens := []Token{
+		{Keyword, "var"},
+		{Whitespace, " "},
+		{Name, "unused"},
+		{Operator, "="},
+		{Name, "a+b"},
+	}
+	it := OverrideTokens(Literator(tokens...), []TokenOverride{
+		{Start: 4, End: 10, Type: GenericDeleted},
+		{Start: 11, End: 12, Type: NameVariable},
+		{Start: 13, End: 14, Type: NameVariable},
+		{Start: 13, End: 14, Type: GenericError},
+	})
+	expected := []Token{
+		{Keyword, "var"},
+		{Whitespace, " "},
+		{GenericDeleted, "unused"},
+		{Operator, "="},
+		{NameVariable, "a"},
+		{Name, "+"},
+		{GenericError, "b"},
+	}
+	assert.Equal(t, expected, it.Tokens())
+}
+
+func TestOverrideTokensSpanningTokens(t *testing.T) {
+	it := OverrideTokens(Literator(Token{Keyword, "var"}, Token{Whitespace, " "}, Token{Name, "x"}), []TokenOverride{{Start: 2, End: 5, Type: GenericEmph}})
+	assert.Equal(t, []Token{{Keyword, "va"}, {GenericEmph, "r"}, {GenericEmph, " "}, {GenericEmph, "x"}}, it.Tokens())
+}
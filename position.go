@@ -0,0 +1,72 @@
+package chroma
+
+import (
+	"unicode/utf8"
+)
+
+// ColumnUnit is the unit in which TokeniseWithPositions counts columns.
+type ColumnUnit int
+
+const (
+	// ColumnRunes counts columns in Unicode code points.
+	ColumnRunes ColumnUnit = iota
+	// ColumnUTF16 counts columns in UTF-16 code units, as the Language Server
+	// Protocol does by default.
+	ColumnUTF16
+	// ColumnBytes counts columns in bytes.
+	ColumnBytes
+)
+
+// A Position in lexed text. Offset is a 0-based byte offset, while Line and
+// Column are 1-based.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// A PositionedToken is a Token annotated with its [Start, End) positions.
+type PositionedToken struct {
+	Token
+	Start, End Position
+}
+
+// TokeniseWithPositions tokenises text as Tokenise does, annotating each token
+// with its start and end positions, with columns counted in columns units.
+//
+// Positions are relative to the text as lexed, ie. after any transformations
+// requested by options or the lexer's Config, such as EnsureLF.
+func TokeniseWithPositions(lexer Lexer, options *TokeniseOptions, text string, columns ColumnUnit) ([]PositionedToken, error) {
+	it, err := lexer.Tokenise(options, text)
+	if err != nil {
+		return nil, err
+	}
+	var out []PositionedToken
+	pos := Position{Line: 1, Column: 1}
+	for t := it(); t != EOF; t = it() {
+		start := pos
+		for i, r := range t.Value {
+			if r == '\n' {
+				pos.Line++
+				pos.Column = 1
+				continue
+			}
+			switch columns {
+			case ColumnUTF16:
+				if r >= 0x10000 {
+					pos.Column += 2
+				} else {
+					pos.Column++
+				}
+			case ColumnBytes:
+				_, size := utf8.DecodeRuneInString(t.Value[i:])
+				pos.Column += size
+			default:
+				pos.Column++
+			}
+		}
+		pos.Offset += len(t.Value)
+		out = append(out, PositionedToken{Token: t, Start: start, End: pos})
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokeniseWithPositions(t *testing.T) {
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`"[^"]*"`, String, nil},
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+	})
+	text := "a \"é😀\"\nb"
+
+	tokens, err := TokeniseWithPositions(l, nil, text, ColumnRunes)
+	require.NoError(t, err)
+	require.Len(t, tokens, 5)
+	assert.Equal(t, PositionedToken{
+		Token: Token{String, "\"é😀\""},
+		Start: Position{Offset: 2, Line: 1, Column: 3},
+		End:   Position{Offset: 10, Line: 1, Column: 7},
+	}, tokens[2])
+	assert.Equal(t, PositionedToken{
+		Token: Token{Name, "b"},
+		Start: Position{Offset: 11, Line: 2, Column: 1},
+		End:   Position{Offset: 12, Line: 2, Column: 2},
+	}, tokens[4])
+
+	tokens, err = TokeniseWithPositions(l, nil, text, ColumnUTF16)
+	require.NoError(t, err)
+	assert.Equal(t, Position{Offset: 10, Line: 1, Column: 8}, tokens[2].End)
+	assert.Equal(t, Position{Offset: 11, Line: 2, Column: 1}, tokens[3].End)
+
+	tokens, err = TokeniseWithPositions(l, nil, text, ColumnBytes)
+	require.NoError(t, err)
+	assert.Equal(t, Position{Offset: 10, Line: 1, Column: 11}, tokens[2].End)
+}
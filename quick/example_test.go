@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/alecthomas/chroma/v2/quick"
 )
 
@@ -17,3 +18,19 @@ func main() { }
 		log.Fatal(err)
 	}
 }
+
+func ExampleHighlightHTML() {
+	code := `package main
+
+func main() { }
+`
+	err := quick.HighlightHTML(os.Stdout, code, "", "monokai", html.WithClasses(true))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// <pre tabindex="0" class="chroma" data-lang="go"><code><span class="line"><span class="cl"><span class="kn">package</span> <span class="nx">main</span>
+	// </span></span><span class="line"><span class="cl">
+	// </span></span><span class="line"><span class="cl"><span class="kd">func</span> <span class="nf">main</span><span class="p">()</span> <span class="p">{</span> <span class="p">}</span>
+	// </span></span></code></pre>
+}
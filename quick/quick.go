@@ -3,9 +3,11 @@ package quick
 
 import (
 	"io"
+	"strings"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
 )
@@ -14,7 +16,29 @@ import (
 //
 // Lexer, formatter and style may be empty, in which case a best-effort is made.
 func Highlight(w io.Writer, source, lexer, formatter, style string) error {
-	// Determine lexer.
+	l := getLexer(source, lexer)
+
+	// Determine formatter.
+	f := formatters.Get(formatter)
+	if f == nil {
+		f = formatters.Fallback
+	}
+
+	return format(w, source, l, f, style)
+}
+
+// HighlightHTML highlights some text as HTML, recording the name of the lexer
+// used in a data-lang attribute, eg. <pre data-lang="go">.
+//
+// Lexer and style may be empty, in which case a best-effort is made.
+func HighlightHTML(w io.Writer, source, lexer, style string, options ...html.Option) error {
+	l := getLexer(source, lexer)
+	lang := strings.ToLower(l.Config().Name)
+	return format(w, source, l, html.New(append(options, html.WithLanguage(lang))...), style)
+}
+
+// getLexer determines the lexer to use, detecting it from source if necessary.
+func getLexer(source, lexer string) chroma.Lexer {
 	l := lexers.Get(lexer)
 	if l == nil {
 		l = lexers.Analyse(source)
@@ -22,13 +46,11 @@ func Highlight(w io.Writer, source, lexer, formatter, style string) error {
 	if l == nil {
 		l = lexers.Fallback
 	}
-	l = chroma.Coalesce(l)
+	return l
+}
 
-	// Determine formatter.
-	f := formatters.Get(formatter)
-	if f == nil {
-		f = formatters.Fallback
-	}
+func format(w io.Writer, source string, l chroma.Lexer, f chroma.Formatter, style string) error {
+	l = chroma.Coalesce(l)
 
 	// Determine style.
 	s := styles.Get(style)
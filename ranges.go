@@ -0,0 +1,66 @@
+package chroma
+
+import (
+	"sort"
+)
+
+// StyleRanges retains token types only within the given [start, end) byte
+// ranges of the source, retyping everything outside them as Text. Tokens are
+// split at range boundaries.
+func StyleRanges(it Iterator, ranges [][2]int) Iterator {
+	ranges = mergeRanges(ranges)
+	offset := 0
+	var queue []Token
+	return func() Token {
+		for len(queue) == 0 {
+			t := it()
+			if t == EOF {
+				return EOF
+			}
+			start, end := offset, offset+len(t.Value)
+			offset = end
+			pos := start
+			for _, r := range ranges {
+				if r[1] <= pos || r[0] >= end {
+					continue
+				}
+				if r[0] > pos {
+					queue = append(queue, Token{Type: Text, Value: t.Value[pos-start : r[0]-start]})
+					pos = r[0]
+				}
+				rangeEnd := r[1]
+				if rangeEnd > end {
+					rangeEnd = end
+				}
+				queue = append(queue, Token{Type: t.Type, Value: t.Value[pos-start : rangeEnd-start]})
+				pos = rangeEnd
+			}
+			if pos < end {
+				queue = append(queue, Token{Type: Text, Value: t.Value[pos-start:]})
+			}
+		}
+		t := queue[0]
+		queue = queue[1:]
+		return t
+	}
+}
+
+// mergeRanges returns a sorted copy of ranges with overlapping ranges merged.
+func mergeRanges(ranges [][2]int) [][2]int {
+	sorted := append([][2]int(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	out := [][2]int{}
+	for _, r := range sorted {
+		if r[0] >= r[1] {
+			continue
+		}
+		if len(out) > 0 && r[0] <= out[len(out)-1][1] {
+			if r[1] > out[len(out)-1][1] {
+				out[len(out)-1][1] = r[1]
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
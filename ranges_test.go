@@ -0,0 +1,44 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStyleRanges(t *testing.T) {
+	// password := "hunter2"; user := "bob"
+	tokens := []Token{
+		{Name, "password"},
+		{Whitespace, " "},
+		{Operator, ":="},
+		{Whitespace, " "},
+		{String, `"hunter2"`},
+		{Punctuation, ";"},
+		{Whitespace, " "},
+		{Name, "user"},
+		{Whitespace, " "},
+		{Operator, ":="},
+		{Whitespace, " "},
+		{String, `"bob"`},
+	}
+	actual := StyleRanges(Literator(tokens...), [][2]int{{31, 36}, {0, 4}, {2, 12}, {25, 27}}).Tokens()
+	assert.Equal(t, []Token{
+		{Name, "password"},
+		{Whitespace, " "},
+		{Operator, ":="},
+		{Whitespace, " "},
+		{Text, `"hunter2"`},
+		{Text, ";"},
+		{Text, " "},
+		{Text, "us"},
+		{Name, "er"},
+		{Text, " "},
+		{Text, ":="},
+		{Text, " "},
+		{String, `"bob"`},
+	}, actual)
+	assert.Equal(t, Stringify(tokens...), Stringify(actual...))
+
+	assert.Equal(t, []Token{{Text, "abc"}}, StyleRanges(Literator(Token{Keyword, "abc"}), nil).Tokens())
+}
@@ -0,0 +1,52 @@
+package chroma
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A Recording of a token stream captured by Record.
+//
+// Recordings can be serialised with MarshalBinary, or as JSON, eg. for
+// attaching to bug reports.
+type Recording struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Record returns an Iterator that passes through tokens from it while
+// capturing them in the returned Recording.
+func Record(it Iterator) (Iterator, *Recording) {
+	recording := &Recording{}
+	return func() Token {
+		t := it()
+		if t != EOF {
+			recording.Tokens = append(recording.Tokens, t)
+		}
+		return t
+	}, recording
+}
+
+// Replay returns an Iterator over the recorded tokens.
+func (r *Recording) Replay() Iterator {
+	return Literator(r.Tokens...)
+}
+
+// MarshalBinary encodes the Recording in the format used by EncodeTokens.
+func (r *Recording) MarshalBinary() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := EncodeTokens(buf, r.Replay()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a Recording encoded with MarshalBinary.
+func (r *Recording) UnmarshalBinary(data []byte) error {
+	it, errf := DecodeTokens(bytes.NewReader(data))
+	tokens := it.Tokens()
+	if err := errf(); err != nil {
+		return fmt.Errorf("invalid recording: %w", err)
+	}
+	r.Tokens = tokens
+	return nil
+}
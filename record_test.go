@@ -0,0 +1,37 @@
+package chroma
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	tokens := []Token{
+		{Keyword, "func"},
+		{Whitespace, " "},
+		{NameFunction, "main"},
+		{Punctuation, "()"},
+		{Text, "\n"},
+	}
+	it, recording := Record(Literator(tokens...))
+	assert.Equal(t, tokens, it.Tokens())
+	assert.Equal(t, tokens, recording.Tokens)
+	assert.Equal(t, tokens, recording.Replay().Tokens())
+	assert.Equal(t, tokens, recording.Replay().Tokens(), "replay is repeatable")
+
+	data, err := recording.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Recording{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, tokens, decoded.Replay().Tokens())
+	assert.Error(t, decoded.UnmarshalBinary(data[:len(data)-1]))
+
+	data, err = json.Marshal(recording)
+	require.NoError(t, err)
+	decoded = &Recording{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, tokens, decoded.Tokens)
+}
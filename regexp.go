@@ -32,6 +32,15 @@ func Words(prefix, suffix string, words ...string) string {
 	return prefix + `(` + strings.Join(words, `|`) + `)` + suffix
 }
 
+// ErrMatchTooLong is the cause of the panic raised by a RegexLexer Iterator if
+// a rule match exceeds TokeniseOptions.MaxMatchLength.
+var ErrMatchTooLong = fmt.Errorf("match too long")
+
+// ErrEmptyMatch is the cause of the panic raised by a RegexLexer Iterator if
+// TokeniseOptions.FailOnEmptyMatch is set and a rule matches the empty string
+// without changing state.
+var ErrEmptyMatch = fmt.Errorf("empty match does not consume input")
+
 // Tokenise text using lexer, returning tokens as a slice.
 func Tokenise(lexer Lexer, options *TokeniseOptions, text string) ([]Token, error) {
 	var out []Token
@@ -47,12 +56,16 @@ func Tokenise(lexer Lexer, options *TokeniseOptions, text string) ([]Token, erro
 
 // TokeniseWithOriginalLen tokenizes the text as Tokenise does, bit also returns an OriginalLenIterator that
 // can be used to calculate the original input token lengths.
+//
+// If lexer does not implement TokeniserWithOriginalLen, eg. one created with
+// NewCustomLexer, the input is assumed to be untransformed and the returned
+// OriginalLenIterator reports token lengths unchanged.
 func TokeniseWithOriginalLen(lexer Lexer, options *TokeniseOptions, text string) ([]Token, OriginalLenIterator, error) {
 	lex, ok := lexer.(TokeniserWithOriginalLen)
 
 	if !ok {
-		err := fmt.Errorf("lexer does not support tokenizing with offsets")
-		return nil, OriginalLenIterator{}, err
+		tokens, err := Tokenise(lexer, options, text)
+		return tokens, OriginalLenIterator{}, err
 	}
 
 	var out []Token
@@ -120,6 +133,12 @@ func NewLexer(config *Config, rulesFunc func() Rules) (*RegexLexer, error) {
 			return nil, fmt.Errorf("%s: %q is not a valid glob: %w", config.Name, glob, err)
 		}
 	}
+	if err := validateAnalyse(config); err != nil {
+		return nil, err
+	}
+	if err := validateFold(config); err != nil {
+		return nil, err
+	}
 	r := &RegexLexer{
 		config:         config,
 		fetchRulesFunc: func() (Rules, error) { return rulesFunc(), nil },
@@ -193,6 +212,10 @@ type LexerState struct {
 	iteratorStack  []Iterator
 	options        *TokeniseOptions
 	newlineAdded   bool
+	// Called before each rule match that begins at the start of a line.
+	lineHook func(l *LexerState)
+	// Position after the last line checked against MaxLineLexLength.
+	nextLineCheck int
 }
 
 // Set mutator context.
@@ -224,6 +247,16 @@ func (l *LexerState) Iterator() Token { // nolint: gocognit
 		}
 
 		l.State = l.Stack[len(l.Stack)-1]
+		if l.lineHook != nil && (l.Pos == 0 || l.Text[l.Pos-1] == '\n') {
+			l.lineHook(l)
+		}
+		if limit := l.options.MaxLineLexLength; limit > 0 && l.Pos >= l.nextLineCheck {
+			if eol := l.overlongLineEnd(end, limit); eol > 0 {
+				value := string(l.Text[l.Pos:eol])
+				l.Pos = eol
+				return Token{Text, value}
+			}
+		}
 		if l.Lexer.trace {
 			fmt.Fprintf(os.Stderr, "%s: pos=%d, text=%q\n", l.State, l.Pos, string(l.Text[l.Pos:]))
 		}
@@ -231,9 +264,9 @@ func (l *LexerState) Iterator() Token { // nolint: gocognit
 		if !ok {
 			panic("unknown state " + l.State)
 		}
-		ruleIndex, rule, groups, namedGroups := matchRules(l.Text, l.Pos, selectedRule)
+		ruleIndex, rule, match := matchRules(l.Text, l.Pos, selectedRule)
 		// No match.
-		if groups == nil {
+		if match == nil {
 			// From Pygments :\
 			//
 			// If the RegexLexer encounters a newline that is flagged as an error token, the stack is
@@ -247,15 +280,39 @@ func (l *LexerState) Iterator() Token { // nolint: gocognit
 			l.Pos++
 			return Token{Error, string(l.Text[l.Pos-1 : l.Pos])}
 		}
+		// Checked before the match's groups are extracted, so an overlong
+		// match is never copied out of the input.
+		if limit := l.options.MaxMatchLength; limit > 0 && match.Length > limit {
+			if !l.options.TruncateLongMatches {
+				panic(fmt.Errorf("%s: match of more than %d characters by rule %d in state %q: %w", l.Lexer.config.Name, limit, ruleIndex, l.State, ErrMatchTooLong))
+			}
+			value := string(l.Text[l.Pos : l.Pos+limit])
+			l.Pos += limit
+			return Token{Error, value}
+		}
+		groups, namedGroups := matchGroups(match)
 		l.Rule = ruleIndex
 		l.Groups = groups
 		l.NamedGroups = namedGroups
 		l.Pos += utf8.RuneCountInString(groups[0])
+		depth := len(l.Stack)
 		if rule.Mutator != nil {
 			if err := rule.Mutator.Mutate(l); err != nil {
 				panic(err)
 			}
 		}
+		// An empty match that leaves the state unchanged would match again forever.
+		if groups[0] == "" && len(l.Stack) == depth && depth > 0 && l.Stack[depth-1] == l.State {
+			err := fmt.Errorf("%s: rule %d in state %q at offset %d: %w", l.Lexer.config.Name, ruleIndex, l.State, l.Pos, ErrEmptyMatch)
+			if l.options.FailOnEmptyMatch {
+				panic(err)
+			}
+			if l.options.OnEmptyMatch != nil {
+				l.options.OnEmptyMatch(err)
+			}
+			l.Pos++
+			return Token{Error, string(l.Text[l.Pos-1 : l.Pos])}
+		}
 		if rule.Type != nil {
 			l.iteratorStack = append(l.iteratorStack, rule.Type.Emit(l.Groups, l))
 		}
@@ -289,6 +346,8 @@ type RegexLexer struct {
 	trace    bool
 
 	mu             sync.Mutex
+	analyseConfig  *AnalyseConfig
+	analyseRegexes []*regexp2.Regexp
 	compiled       bool
 	rawRules       Rules
 	rules          map[string][]*CompiledRule
@@ -324,7 +383,50 @@ func (r *RegexLexer) AnalyseText(text string) float32 { // nolint
 	if r.analyser != nil {
 		return r.analyser(text)
 	}
-	return 0.0
+	if r.config.Analyse == nil {
+		return 0.0
+	}
+	score := float32(0.0)
+	for i, re := range r.compileAnalyse() {
+		if ok, _ := re.MatchString(text); !ok {
+			continue
+		}
+		if r.config.Analyse.First {
+			return r.config.Analyse.Regexes[i].Score
+		}
+		score += r.config.Analyse.Regexes[i].Score
+	}
+	if score > 1.0 {
+		score = 1.0
+	}
+	return score
+}
+
+// compileAnalyse compiles the regexes in Config.Analyse, recompiling if the Config has changed.
+func (r *RegexLexer) compileAnalyse() []*regexp2.Regexp {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.analyseConfig != r.config.Analyse {
+		r.analyseConfig = r.config.Analyse
+		r.analyseRegexes = make([]*regexp2.Regexp, len(r.analyseConfig.Regexes))
+		for i, rc := range r.analyseConfig.Regexes {
+			// Patterns are validated when the Lexer is created.
+			r.analyseRegexes[i] = regexp2.MustCompile(rc.Pattern, regexp2.Multiline)
+		}
+	}
+	return r.analyseRegexes
+}
+
+func validateAnalyse(config *Config) error {
+	if config.Analyse == nil {
+		return nil
+	}
+	for _, rc := range config.Analyse.Regexes {
+		if _, err := regexp2.Compile(rc.Pattern, regexp2.Multiline); err != nil {
+			return fmt.Errorf("%s: invalid analyse pattern %q: %w", config.Name, rc.Pattern, err)
+		}
+	}
+	return nil
 }
 
 // SetConfig replaces the Config for this Lexer.
@@ -358,6 +460,9 @@ func (r *RegexLexer) maybeCompile() (err error) {
 				}
 				rule.Regexp.MatchTimeout = time.Millisecond * 250
 			}
+			if err := validateEmitter(rule.Type); err != nil {
+				return fmt.Errorf("rule %s.%d: %w", state, i, err)
+			}
 		}
 	}
 restart:
@@ -442,17 +547,69 @@ func (r *RegexLexer) TokeniseWithOriginalLen(options *TokeniseOptions, text stri
 		options = defaultOptions
 	}
 
+	if options.Dedent {
+		text = dedent(text)
+	}
 	var offsets offsetMap
 	if options.EnsureLF {
 		text, offsets = ensureLF(text)
 	}
 
+	state := r.newLexerState(options, text)
+	it := Iterator(state.Iterator)
+	if options.PreserveEOF && state.newlineAdded {
+		it = trimTrailingNewline(it)
+	}
+	if len(options.ExtraKeywords) > 0 {
+		it = retypeKeywords(it, options.ExtraKeywords)
+	}
+	return it, offsets.iterator(), nil
+}
+
+// retypeKeywords retypes Name tokens whose value is exactly a key of keywords.
+func retypeKeywords(it Iterator, keywords map[string]TokenType) Iterator {
+	return func() Token {
+		t := it()
+		if t.Type.InCategory(Name) {
+			if tt, ok := keywords[t.Value]; ok {
+				t.Type = tt
+			}
+		}
+		return t
+	}
+}
+
+// trimTrailingNewline removes a trailing newline from the last token of it.
+func trimTrailingNewline(it Iterator) Iterator {
+	var next Token
+	started := false
+	return func() Token {
+		if !started {
+			next = it()
+			started = true
+		}
+		t := next
+		if t == EOF {
+			return EOF
+		}
+		next = it()
+		if next == EOF && strings.HasSuffix(t.Value, "\n") {
+			t.Value = strings.TrimSuffix(t.Value, "\n")
+			if t.Value == "" {
+				return EOF
+			}
+		}
+		return t
+	}
+}
+
+func (r *RegexLexer) newLexerState(options *TokeniseOptions, text string) *LexerState {
 	newlineAdded := false
 	if !options.Nested && r.config.EnsureNL && !strings.HasSuffix(text, "\n") {
 		text += "\n"
 		newlineAdded = true
 	}
-	state := &LexerState{
+	return &LexerState{
 		Registry:       r.registry,
 		newlineAdded:   newlineAdded,
 		options:        options,
@@ -462,7 +619,6 @@ func (r *RegexLexer) TokeniseWithOriginalLen(options *TokeniseOptions, text stri
 		Rules:          r.rules,
 		MutatorContext: map[interface{}]interface{}{},
 	}
-	return state.Iterator, offsets.iterator(), nil
 }
 
 // MustRules is like Rules() but will panic on error.
@@ -474,20 +630,82 @@ func (r *RegexLexer) MustRules() Rules {
 	return rules
 }
 
-func matchRules(text []rune, pos int, rules []*CompiledRule) (int, *CompiledRule, []string, map[string]string) {
+func matchRules(text []rune, pos int, rules []*CompiledRule) (int, *CompiledRule, *regexp2.Match) {
 	for i, rule := range rules {
 		match, err := rule.Regexp.FindRunesMatchStartingAt(text, pos)
 		if match != nil && err == nil && match.Index == pos {
-			groups := []string{}
-			namedGroups := make(map[string]string)
-			for _, g := range match.Groups() {
-				namedGroups[g.Name] = g.String()
-				groups = append(groups, g.String())
-			}
-			return i, rule, groups, namedGroups
+			return i, rule, match
+		}
+	}
+	return 0, &CompiledRule{}, nil
+}
+
+func matchGroups(match *regexp2.Match) ([]string, map[string]string) {
+	groups := []string{}
+	namedGroups := make(map[string]string)
+	for _, g := range match.Groups() {
+		namedGroups[g.Name] = g.String()
+		groups = append(groups, g.String())
+	}
+	return groups, namedGroups
+}
+
+// overlongLineEnd returns the position after the line containing l.Pos,
+// including its newline, if that line is longer than limit characters, or 0.
+//
+// The line is found from l.Pos rather than by checking for a preceding
+// newline, as a rule may consume a newline along with the following
+// indentation. Each line is only checked once.
+func (l *LexerState) overlongLineEnd(end, limit int) int {
+	start := l.Pos
+	for start > l.nextLineCheck && l.Text[start-1] != '\n' {
+		start--
+	}
+	eol := start
+	for eol < end && l.Text[eol] != '\n' {
+		eol++
+	}
+	length := eol - start
+	if eol < end {
+		eol++
+	}
+	l.nextLineCheck = eol
+	if length <= limit {
+		return 0
+	}
+	return eol
+}
+
+// dedent removes the longest common leading whitespace from all non-blank
+// lines of text. Whitespace-only lines are stripped of leading whitespace.
+func dedent(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	indent := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			indent, first = lineIndent, false
+			continue
+		}
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+	if indent == "" {
+		return text
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = strings.TrimLeft(line, " \t")
+		} else {
+			lines[i] = line[len(indent):]
 		}
 	}
-	return 0, &CompiledRule{}, nil, nil
+	return strings.Join(lines, "")
 }
 
 // replace \r and \r\n with \n
@@ -1,7 +1,9 @@
 package chroma
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -366,3 +368,210 @@ func TestTokenizeWithOffsetsForDelegatingLexer(t *testing.T) {
 		checkLenInBytes(&tok, &lengthsIter, i)
 	}
 }
+
+func TestPreserveEOF(t *testing.T) {
+	l := mustNewLexer(t, &Config{EnsureNL: true}, Rules{ // nolint: forbidigo
+		"root": {
+			{`(\w+)(\n)`, ByGroups(Keyword, Whitespace), nil},
+			{`\s+`, Whitespace, nil},
+		},
+	})
+	options := &TokeniseOptions{State: "root", PreserveEOF: true}
+	for _, text := range []string{"hello", "hello\n", "hello\nworld", "hello\nworld\n"} {
+		tokens, err := Tokenise(l, options, text)
+		assert.NoError(t, err)
+		assert.Equal(t, text, Stringify(tokens...))
+	}
+	tokens, err := Tokenise(l, nil, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", Stringify(tokens...))
+}
+
+func TestMaxMatchLength(t *testing.T) {
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`"[^"]*"`, String, nil},
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+	})
+	text := `a "` + strings.Repeat("x", 100) + `é" b`
+
+	tokens, err := Tokenise(l, &TokeniseOptions{State: "root"}, text)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 5)
+
+	it, err := l.Tokenise(&TokeniseOptions{State: "root", MaxMatchLength: 16}, text)
+	assert.NoError(t, err)
+	assert.PanicsWithError(t, `: match of more than 16 characters by rule 0 in state "root": match too long`, func() { it.Tokens() })
+
+	tokens, err = Tokenise(l, &TokeniseOptions{State: "root", MaxMatchLength: 16, TruncateLongMatches: true}, text)
+	assert.NoError(t, err)
+	assert.Equal(t, text, Stringify(tokens...))
+	// The string rule is the one truncated, rather than a later rule.
+	assert.Equal(t, Token{Error, `"` + strings.Repeat("x", 15)}, tokens[2])
+	for _, token := range tokens {
+		assert.LessOrEqual(t, utf8.RuneCountInString(token.Value), 16)
+	}
+
+	// The limit is in characters, not bytes.
+	tokens, err = Tokenise(l, &TokeniseOptions{State: "root", MaxMatchLength: 4}, `"éé"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{{String, `"éé"`}}, tokens)
+}
+
+func TestMaxMatchLengthGreedyRule(t *testing.T) {
+	// Within any window short of the closing quote the greedy string rule
+	// cannot match, and the identifier rule would silently take over.
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`"[^"]*"`, String, nil},
+			{`"?\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+	})
+	text := `"` + strings.Repeat("x", 20) + " " + strings.Repeat("y", 20) + `"`
+
+	it, err := l.Tokenise(&TokeniseOptions{State: "root", MaxMatchLength: 16}, text)
+	assert.NoError(t, err)
+	assert.PanicsWithError(t, `: match of more than 16 characters by rule 0 in state "root": match too long`, func() { it.Tokens() })
+
+	tokens, err := Tokenise(l, &TokeniseOptions{State: "root", MaxMatchLength: 16, TruncateLongMatches: true}, text)
+	assert.NoError(t, err)
+	assert.Equal(t, text, Stringify(tokens...))
+	assert.Equal(t, Token{Error, `"` + strings.Repeat("x", 15)}, tokens[0])
+}
+
+func TestEmptyMatch(t *testing.T) {
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`\w+`, Name, nil},
+			{`\s*`, Whitespace, nil},
+		},
+	})
+	var warnings []error
+	tokens, err := Tokenise(l, &TokeniseOptions{State: "root", OnEmptyMatch: func(err error) { warnings = append(warnings, err) }}, "a+b")
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{{Name, "a"}, {Error, "+"}, {Name, "b"}}, tokens)
+	assert.Len(t, warnings, 1)
+	assert.ErrorIs(t, warnings[0], ErrEmptyMatch)
+
+	it, err := l.Tokenise(&TokeniseOptions{State: "root", FailOnEmptyMatch: true}, "a+b")
+	assert.NoError(t, err)
+	assert.PanicsWithError(t, `: rule 1 in state "root" at offset 1: empty match does not consume input`, func() { it.Tokens() })
+
+	// Empty matches that change state are fine.
+	l = mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`\w+`, Name, nil},
+			{``, nil, Push("other")},
+		},
+		"other": {
+			{`\+`, Operator, Pop(1)},
+		},
+	})
+	tokens, err = Tokenise(l, &TokeniseOptions{State: "root", FailOnEmptyMatch: true}, "a+b")
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{{Name, "a"}, {Operator, "+"}, {Name, "b"}}, tokens)
+}
+
+func TestExtraKeywords(t *testing.T) {
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`"[^"]*"`, String, nil},
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+	})
+	options := &TokeniseOptions{State: "root", ExtraKeywords: map[string]TokenType{"ledger": KeywordType, "post": NameBuiltin}}
+	tokens, err := Tokenise(l, options, `ledger ledgers post "post"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{
+		{KeywordType, "ledger"},
+		{Whitespace, " "},
+		{Name, "ledgers"},
+		{Whitespace, " "},
+		{NameBuiltin, "post"},
+		{Whitespace, " "},
+		{String, `"post"`},
+	}, tokens)
+}
+
+func TestMaxLineLexLength(t *testing.T) {
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`/\*`, CommentMultiline, Push("comment")},
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+			{`.`, Punctuation, nil},
+		},
+		"comment": {
+			{`\*/`, CommentMultiline, Pop(1)},
+			{`[^*]+?`, CommentMultiline, nil},
+		},
+	})
+	long := strings.Repeat("x.", 10)
+	text := "a b\n" + long + "\n/* c\n" + long + "\n*/ d"
+	tokens, err := Tokenise(Coalesce(l), &TokeniseOptions{State: "root", MaxLineLexLength: 10}, text)
+	assert.NoError(t, err)
+	assert.Equal(t, text, Stringify(tokens...))
+	assert.Equal(t, []Token{
+		{Name, "a"}, {Whitespace, " "}, {Name, "b"}, {Whitespace, "\n"},
+		{Text, long + "\n"},
+		{CommentMultiline, "/* c\n"},
+		// The lexer is still within the comment after the skipped line.
+		{Text, long + "\n"},
+		{CommentMultiline, "*/"}, {Whitespace, " "}, {Name, "d"},
+	}, tokens)
+}
+
+func TestMaxLineLexLengthIndented(t *testing.T) {
+	l := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`\s+`, Whitespace, nil},
+			{`"[^"]*"`, String, nil},
+			{`.`, Punctuation, nil},
+		},
+	})
+	long := strings.Repeat(`"x", `, 10)
+	text := "[\n    " + long + "\n    \"y\"\n]"
+	tokens, err := Tokenise(l, &TokeniseOptions{State: "root", MaxLineLexLength: 20}, text)
+	assert.NoError(t, err)
+	assert.Equal(t, text, Stringify(tokens...))
+	assert.Equal(t, []Token{
+		{Punctuation, "["}, {Whitespace, "\n    "},
+		{Text, long + "\n"},
+		{Whitespace, "    "}, {String, `"y"`}, {Whitespace, "\n"},
+		{Punctuation, "]"},
+	}, tokens)
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"a\n  b\n", "a\n  b\n"},
+		{"    if x:\n        y\n\n    z\n", "if x:\n    y\n\nz\n"},
+		{"\t\ta\n\t\t\tb", "a\n\tb"},
+		{"  a\n \n      \n  b\n", "a\n\n\nb\n"},
+		{"\t a\n\t  b\n", "a\n b\n"},
+		{"\t a\n  b\n", "\t a\n  b\n"},
+		{"  a\r\n  b\r\n", "a\r\nb\r\n"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.out, dedent(test.in), "%q", test.in)
+	}
+
+	l := Coalesce(mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+	}))
+	tokens, err := Tokenise(l, &TokeniseOptions{State: "root", Dedent: true}, "    if x\n        y\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Name, "if"}, {Whitespace, " "}, {Name, "x"}, {Whitespace, "\n    "}, {Name, "y"}, {Whitespace, "\n"},
+	}, tokens)
+}
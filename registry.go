@@ -2,8 +2,11 @@ package chroma
 
 import (
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -24,13 +27,25 @@ type LexerRegistry struct {
 	Lexers  Lexers
 	byName  map[string]Lexer
 	byAlias map[string]Lexer
+	magic   []magicSignature
+	// Exact filenames mapped to lexer names by MapFilename.
+	filenames map[string]string
+	// Returned by Get and Match when no other Lexer is found.
+	fallback Lexer
+}
+
+// A magicSignature maps a leading byte sequence to a Lexer.
+type magicSignature struct {
+	magic string
+	lexer Lexer
 }
 
 // NewLexerRegistry creates a new LexerRegistry of Lexers.
 func NewLexerRegistry() *LexerRegistry {
 	return &LexerRegistry{
-		byName:  map[string]Lexer{},
-		byAlias: map[string]Lexer{},
+		byName:    map[string]Lexer{},
+		byAlias:   map[string]Lexer{},
+		filenames: map[string]string{},
 	}
 }
 
@@ -48,8 +63,26 @@ func (l *LexerRegistry) Names(withAliases bool) []string {
 	return out
 }
 
+// SetFallback sets a Lexer returned by Get and Match when no other Lexer is
+// found, instead of nil.
+func (l *LexerRegistry) SetFallback(lexer Lexer) {
+	l.fallback = lexer
+}
+
+// Fallback returns the Lexer set by SetFallback, or nil.
+func (l *LexerRegistry) Fallback() Lexer {
+	return l.fallback
+}
+
 // Get a Lexer by name, alias or file extension.
 func (l *LexerRegistry) Get(name string) Lexer {
+	if lexer := l.get(name); lexer != nil {
+		return lexer
+	}
+	return l.fallback
+}
+
+func (l *LexerRegistry) get(name string) Lexer {
 	if lexer := l.byName[name]; lexer != nil {
 		return lexer
 	}
@@ -65,11 +98,11 @@ func (l *LexerRegistry) Get(name string) Lexer {
 
 	candidates := PrioritisedLexers{}
 	// Try file extension.
-	if lexer := l.Match("filename." + name); lexer != nil {
+	if lexer := l.match("filename." + name); lexer != nil {
 		candidates = append(candidates, lexer)
 	}
 	// Try exact filename.
-	if lexer := l.Match(name); lexer != nil {
+	if lexer := l.match(name); lexer != nil {
 		candidates = append(candidates, lexer)
 	}
 	if len(candidates) == 0 {
@@ -98,7 +131,19 @@ func (l *LexerRegistry) MatchMimeType(mimeType string) Lexer {
 
 // Match returns the first lexer matching filename.
 func (l *LexerRegistry) Match(filename string) Lexer {
+	if lexer := l.match(filename); lexer != nil {
+		return lexer
+	}
+	return l.fallback
+}
+
+func (l *LexerRegistry) match(filename string) Lexer {
 	filename = filepath.Base(filename)
+	if name, ok := l.filenames[filename]; ok {
+		if lexer := l.get(name); lexer != nil {
+			return lexer
+		}
+	}
 	matched := PrioritisedLexers{}
 	// First, try primary filename matches.
 	for _, lexer := range l.Lexers {
@@ -156,8 +201,43 @@ func (l *LexerRegistry) Match(filename string) Lexer {
 	return nil
 }
 
+// MapFilename maps an exact filename, eg. "Jenkinsfile", to the Lexer with the
+// given name or alias. Mappings take precedence over filename globs in Match.
+func (l *LexerRegistry) MapFilename(filename, lexerName string) {
+	l.filenames[filename] = lexerName
+}
+
+// RegisterMagic associates a Lexer with content that begins with the given
+// magic bytes, eg. "<?xml" for XML.
+//
+// Magic signatures are consulted by Analyse before any statistical analysis.
+func (l *LexerRegistry) RegisterMagic(magic string, lexer Lexer) {
+	l.magic = append(l.magic, magicSignature{magic: magic, lexer: lexer})
+}
+
+// MatchMagic returns the Lexer whose magic signature prefixes text, or nil.
+//
+// A leading UTF-8 byte order mark is ignored. If several signatures match,
+// the longest wins.
+func (l *LexerRegistry) MatchMagic(text string) Lexer {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	var picked *magicSignature
+	for i, sig := range l.magic {
+		if strings.HasPrefix(text, sig.magic) && (picked == nil || len(sig.magic) > len(picked.magic)) {
+			picked = &l.magic[i]
+		}
+	}
+	if picked == nil {
+		return nil
+	}
+	return picked.lexer
+}
+
 // Analyse text content and return the "best" lexer..
 func (l *LexerRegistry) Analyse(text string) Lexer {
+	if lexer := l.MatchMagic(text); lexer != nil {
+		return lexer
+	}
 	var picked Lexer
 	highest := float32(0.0)
 	for _, lexer := range l.Lexers {
@@ -172,6 +252,149 @@ func (l *LexerRegistry) Analyse(text string) Lexer {
 	return picked
 }
 
+// DetectionMethod is how DetectBest found a Lexer.
+type DetectionMethod int
+
+// Detection methods, in the order DetectBest tries them.
+const (
+	// DetectedNone means no Lexer was found.
+	DetectedNone DetectionMethod = iota
+	// DetectedFilename means the Lexer matched the filename.
+	DetectedFilename
+	// DetectedFirstLine means the Lexer matched a magic signature or the
+	// interpreter of a "#!" line.
+	DetectedFirstLine
+	// DetectedAnalysis means the Lexer scored highest in content analysis.
+	DetectedAnalysis
+)
+
+func (d DetectionMethod) String() string {
+	switch d {
+	case DetectedFilename:
+		return "filename"
+	case DetectedFirstLine:
+		return "first-line"
+	case DetectedAnalysis:
+		return "analysis"
+	default:
+		return "none"
+	}
+}
+
+// DetectBest returns the best Lexer for a file, trying in turn its filename,
+// its first line, and finally analysis of content, along with the method that
+// found it. filename may be empty.
+//
+// If no Lexer is found, the fallback Lexer, if any, is returned with
+// DetectedNone.
+func (l *LexerRegistry) DetectBest(filename string, content []byte) (Lexer, DetectionMethod) {
+	if filename != "" {
+		if lexer := l.match(filename); lexer != nil {
+			return lexer, DetectedFilename
+		}
+	}
+	text := string(content)
+	if lexer := l.MatchMagic(text); lexer != nil {
+		return lexer, DetectedFirstLine
+	}
+	if lexer := l.matchShebang(text); lexer != nil {
+		return lexer, DetectedFirstLine
+	}
+	if lexer := l.Analyse(text); lexer != nil {
+		return lexer, DetectedAnalysis
+	}
+	return l.fallback, DetectedNone
+}
+
+// matchShebang returns the Lexer for the interpreter named by a leading "#!"
+// line, eg. "#!/usr/bin/env python3", or nil.
+func (l *LexerRegistry) matchShebang(text string) Lexer {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	if !strings.HasPrefix(text, "#!") {
+		return nil
+	}
+	if eol := strings.IndexByte(text, '\n'); eol >= 0 {
+		text = text[:eol]
+	}
+	fields := strings.Fields(text[2:])
+	if len(fields) == 0 {
+		return nil
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+	if interpreter == "" {
+		return nil
+	}
+	if lexer := l.get(interpreter); lexer != nil {
+		return lexer
+	}
+	// Strip any version, eg. python3.11.
+	if trimmed := strings.TrimRight(interpreter, "0123456789."); trimmed != "" && trimmed != interpreter {
+		return l.get(trimmed)
+	}
+	return nil
+}
+
+// AnalyseParallel is like Analyse but runs lexer analysers concurrently across
+// workers goroutines. If workers <= 0, runtime.NumCPU() is used.
+//
+// If threshold is > 0, analysis stops as soon as any lexer scores at least
+// threshold, in which case the result may differ from Analyse. Otherwise the
+// result is identical to Analyse.
+func (l *LexerRegistry) AnalyseParallel(text string, workers int, threshold float32) Lexer {
+	if lexer := l.MatchMagic(text); lexer != nil {
+		return lexer
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	weights := make([]float32, len(l.Lexers))
+	var (
+		next int64 = -1
+		done int32
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&done) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(l.Lexers) {
+					return
+				}
+				analyser, ok := l.Lexers[i].(Analyser)
+				if !ok {
+					continue
+				}
+				weights[i] = analyser.AnalyseText(text)
+				if threshold > 0 && weights[i] >= threshold {
+					atomic.StoreInt32(&done, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// Pick in registration order so ties resolve as in Analyse.
+	var picked Lexer
+	highest := float32(0.0)
+	for i, weight := range weights {
+		if weight > highest {
+			picked = l.Lexers[i]
+			highest = weight
+		}
+	}
+	return picked
+}
+
 // Register a Lexer with the LexerRegistry.
 func (l *LexerRegistry) Register(lexer Lexer) Lexer {
 	lexer.SetRegistry(l)
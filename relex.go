@@ -0,0 +1,116 @@
+package chroma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LineState is a snapshot of the state of a RegexLexer at the start of a line.
+//
+// See RegexLexer.TokeniseWithLineStates and ReLexLine.
+type LineState struct {
+	lexer   *RegexLexer
+	options *TokeniseOptions
+	stack   []string
+	context map[interface{}]interface{}
+}
+
+// Resumable returns true if lexing can be resumed from this state.
+//
+// This is false for lines that begin part way through a single rule match,
+// such as a multi-line comment.
+func (s LineState) Resumable() bool {
+	return s.stack != nil
+}
+
+// Stack of states in effect at the start of the line.
+func (s LineState) Stack() []string {
+	return s.stack
+}
+
+func snapshotLineState(l *LexerState) LineState {
+	context := make(map[interface{}]interface{}, len(l.MutatorContext))
+	for k, v := range l.MutatorContext {
+		context[k] = v
+	}
+	return LineState{
+		lexer:   l.Lexer,
+		options: l.options,
+		stack:   append([]string(nil), l.Stack...),
+		context: context,
+	}
+}
+
+// TokeniseWithLineStates tokenises text, additionally returning a snapshot of
+// the lexer state at the start of each line.
+//
+// The snapshots can be passed to ReLexLine to re-lex a single edited line
+// without re-lexing the whole text. The tokens always cover exactly text: the
+// newline an EnsureNL lexer adds to text without one is not included.
+func (r *RegexLexer) TokeniseWithLineStates(options *TokeniseOptions, text string) ([]Token, []LineState, error) {
+	if err := r.needRules(); err != nil {
+		return nil, nil, err
+	}
+	if options == nil {
+		options = defaultOptions
+	}
+	if options.EnsureLF {
+		text, _ = ensureLF(text)
+	}
+	lineStarts := map[int]int{0: 0}
+	line := 0
+	for i, c := range []rune(text) {
+		if c == '\n' {
+			line++
+			lineStarts[i+1] = line
+		}
+	}
+	states := make([]LineState, strings.Count(text, "\n")+1)
+	state := r.newLexerState(options, text)
+	state.lineHook = func(l *LexerState) {
+		states[lineStarts[l.Pos]] = snapshotLineState(l)
+	}
+	tokens := lineStateIterator(state).Tokens()
+	// Trailing empty line.
+	if strings.HasSuffix(text, "\n") {
+		states = states[:len(states)-1]
+	}
+	return tokens, states, nil
+}
+
+// ReLexLine tokenises a single line of text starting from a state previously
+// captured by RegexLexer.TokeniseWithLineStates.
+//
+// The line should include its trailing newline, if any. As with
+// TokeniseWithLineStates, the newline an EnsureNL lexer adds to a final line
+// without one is not included in the tokens. The state at the end of the line
+// is also returned, which can be compared with the original state of the
+// following line to determine whether it also needs to be re-lexed. If the
+// following line is not Resumable, a match spans the line boundary and the
+// lines must be re-lexed together.
+func ReLexLine(state LineState, line string) ([]Token, LineState, error) {
+	if !state.Resumable() {
+		return nil, LineState{}, fmt.Errorf("line does not start on a token boundary")
+	}
+	if state.options.EnsureLF {
+		line, _ = ensureLF(line)
+	}
+	l := state.lexer.newLexerState(state.options, line)
+	l.Stack = append([]string(nil), state.stack...)
+	for k, v := range state.context {
+		l.MutatorContext[k] = v
+	}
+	tokens := lineStateIterator(l).Tokens()
+	return tokens, snapshotLineState(l), nil
+}
+
+// lineStateIterator returns an Iterator over the tokens of l, excluding any
+// newline synthesised for an EnsureNL lexer, so that the tokens of a final
+// line without a newline match whether it is lexed alone or with the text.
+func lineStateIterator(l *LexerState) Iterator {
+	it := Iterator(l.Iterator)
+	if l.newlineAdded {
+		it = trimTrailingNewline(it)
+	}
+	return it
+}
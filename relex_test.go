@@ -0,0 +1,84 @@
+package chroma
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReLexLine(t *testing.T) {
+	lexer := mustNewLexer(t, nil, Rules{ // nolint: forbidigo
+		"root": {
+			{`"`, String, Push("string")},
+			{`(?s)/\*.*?\*/`, CommentMultiline, nil},
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+		"string": {
+			{`"`, String, Pop(1)},
+			{`[^"\n]+`, String, nil},
+			{`\n`, String, nil},
+		},
+	})
+	text := "a \"b\nc\" d\n/* e\nf */ g\nh\n"
+	tokens, states, err := lexer.TokeniseWithLineStates(nil, text)
+	require.NoError(t, err)
+	require.Len(t, states, 5)
+	assert.Equal(t, []string{"root"}, states[0].Stack())
+	assert.Equal(t, []string{"root", "string"}, states[1].Stack())
+	assert.Equal(t, []string{"root"}, states[2].Stack())
+	assert.False(t, states[3].Resumable())
+
+	lines := strings.SplitAfter(text, "\n")
+	expected := SplitTokensIntoLines(tokens)
+	for i, line := range expected {
+		// Splitting leaves empty tokens at the start of lines.
+		if len(line) > 0 && line[0].Value == "" {
+			expected[i] = line[1:]
+		}
+	}
+	for i, state := range states {
+		if !state.Resumable() {
+			_, _, err := ReLexLine(state, lines[i])
+			assert.Error(t, err)
+			continue
+		}
+		actual, next, err := ReLexLine(state, lines[i])
+		require.NoError(t, err)
+		// A match continuing onto the next line can't be reproduced from this line alone.
+		if i+1 < len(states) && !states[i+1].Resumable() {
+			continue
+		}
+		assert.Equal(t, expected[i], actual, "line %d", i)
+		if i+1 < len(states) {
+			assert.Equal(t, states[i+1].Stack(), next.Stack(), "line %d", i)
+		}
+	}
+}
+
+func TestReLexLineEnsureNL(t *testing.T) {
+	lexer := mustNewLexer(t, &Config{EnsureNL: true}, Rules{ // nolint: forbidigo
+		"root": {
+			{`#[^\n]*\n`, Comment, nil},
+			{`\w+`, Name, nil},
+			{`\s+`, Whitespace, nil},
+		},
+	})
+	text := "a\n# b"
+	tokens, states, err := lexer.TokeniseWithLineStates(nil, text)
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, text, Stringify(tokens...))
+
+	actual, _, err := ReLexLine(states[1], "# b")
+	require.NoError(t, err)
+	assert.Equal(t, []Token{{Comment, "# b"}}, actual)
+	assert.Equal(t, tokens[len(tokens)-1], actual[0])
+
+	// A line with its newline is lexed as is.
+	actual, _, err = ReLexLine(states[1], "# b\n")
+	require.NoError(t, err)
+	assert.Equal(t, []Token{{Comment, "# b\n"}}, actual)
+}
@@ -54,6 +54,12 @@ import (
 var (
 	// ErrNotSerialisable is returned if a lexer contains Rules that cannot be serialised.
 	ErrNotSerialisable = fmt.Errorf("not serialisable")
+	// ErrTooManyStates is returned when loading a lexer that defines more than MaxStates states.
+	ErrTooManyStates = fmt.Errorf("too many states")
+	// MaxStates is the maximum number of distinct states a lexer loaded with
+	// Unmarshal, NewXMLLexer or LoadCompiled may define, guarding against
+	// hostile lexer definitions. A value <= 0 disables the limit.
+	MaxStates = 1000
 	emitterTemplates   = func() map[string]SerialisableEmitter {
 		out := map[string]SerialisableEmitter{}
 		for _, emitter := range []SerialisableEmitter{
@@ -62,6 +68,7 @@ var (
 			TokenType(0),
 			&usingEmitter{},
 			&usingByGroup{},
+			&usingIfEmitter{},
 		} {
 			out[emitter.EmitterKind()] = emitter
 		}
@@ -124,7 +131,56 @@ func MustNewXMLLexer(from fs.FS, path string) *RegexLexer {
 }
 
 // NewXMLLexer creates a new RegexLexer from a serialised RegexLexer.
+//
+// The whole definition is parsed and its rules compiled up front, so that an
+// invalid definition is rejected here rather than on first use.
 func NewXMLLexer(from fs.FS, path string) (*RegexLexer, error) {
+	config, err := loadXMLConfig(from, path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := unmarshalXMLRules(from, path)
+	if err != nil {
+		return nil, err
+	}
+	lexer := &RegexLexer{
+		config:         config,
+		fetchRulesFunc: func() (Rules, error) { return rules, nil },
+	}
+	if err := lexer.needRules(); err != nil {
+		return nil, err
+	}
+	return lexer, nil
+}
+
+// MustNewLazyXMLLexer constructs a new lazily loaded RegexLexer from an XML
+// file or panics.
+func MustNewLazyXMLLexer(from fs.FS, path string) *RegexLexer {
+	lex, err := NewLazyXMLLexer(from, path)
+	if err != nil {
+		panic(err)
+	}
+	return lex
+}
+
+// NewLazyXMLLexer is like NewXMLLexer, but only reads the lexer's Config up
+// front, deferring parsing of its rules until the lexer is first used.
+//
+// This avoids significant init() time costs for trusted definitions, such as
+// those embedded in Chroma, but errors in the rules are only reported on first
+// use.
+func NewLazyXMLLexer(from fs.FS, path string) (*RegexLexer, error) {
+	config, err := loadXMLConfig(from, path)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexLexer{
+		config:         config,
+		fetchRulesFunc: func() (Rules, error) { return unmarshalXMLRules(from, path) },
+	}, nil
+}
+
+func loadXMLConfig(from fs.FS, path string) (*Config, error) {
 	config, err := fastUnmarshalConfig(from, path)
 	if err != nil {
 		return nil, err
@@ -135,41 +191,46 @@ func NewXMLLexer(from fs.FS, path string) (*RegexLexer, error) {
 			return nil, fmt.Errorf("%s: %q is not a valid glob: %w", config.Name, glob, err)
 		}
 	}
-	return &RegexLexer{
-		config: config,
-		fetchRulesFunc: func() (Rules, error) {
-			var lexer struct {
-				Config
-				Rules Rules `xml:"rules"`
-			}
-			// Try to open .xml fallback to .xml.gz
-			fr, err := from.Open(path)
-			if err != nil {
-				if errors.Is(err, fs.ErrNotExist) {
-					path += ".gz"
-					fr, err = from.Open(path)
-					if err != nil {
-						return nil, err
-					}
-				} else {
-					return nil, err
-				}
-			}
-			defer fr.Close()
-			var r io.Reader = fr
-			if strings.HasSuffix(path, ".gz") {
-				r, err = gzip.NewReader(r)
-				if err != nil {
-					return nil, fmt.Errorf("%s: %w", path, err)
-				}
-			}
-			err = xml.NewDecoder(r).Decode(&lexer)
+	if err := validateAnalyse(config); err != nil {
+		return nil, err
+	}
+	if err := validateFold(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func unmarshalXMLRules(from fs.FS, path string) (Rules, error) {
+	var lexer struct {
+		Config
+		Rules Rules `xml:"rules"`
+	}
+	// Try to open .xml fallback to .xml.gz
+	fr, err := from.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			path += ".gz"
+			fr, err = from.Open(path)
 			if err != nil {
-				return nil, fmt.Errorf("%s: %w", path, err)
+				return nil, err
 			}
-			return lexer.Rules, nil
-		},
-	}, nil
+		} else {
+			return nil, err
+		}
+	}
+	defer fr.Close()
+	var r io.Reader = fr
+	if strings.HasSuffix(path, ".gz") {
+		r, err = gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	err = xml.NewDecoder(r).Decode(&lexer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lexer.Rules, nil
 }
 
 // Marshal a RegexLexer to XML.
@@ -349,16 +410,42 @@ func (r Rules) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
 	return e.EncodeElement(xr, xml.StartElement{Name: xml.Name{Local: "rules"}})
 }
 
-func (r *Rules) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	xr := xmlRules{}
-	if err := d.DecodeElement(&xr, &start); err != nil {
-		return err
-	}
+func (r *Rules) UnmarshalXML(d *xml.Decoder, _ xml.StartElement) error {
 	if *r == nil {
 		*r = Rules{}
 	}
-	for _, state := range xr.States {
-		(*r)[state.Name] = state.Rules
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if token.Name.Local != "state" {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			state := xmlRuleState{}
+			if err := d.DecodeElement(&state, &token); err != nil {
+				return err
+			}
+			(*r)[state.Name] = state.Rules
+			// Abort as soon as the limit is exceeded, rather than decoding the rest.
+			if err := validateStateCount(*r); err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func validateStateCount(rules Rules) error {
+	if MaxStates > 0 && len(rules) > MaxStates {
+		return fmt.Errorf("%d states exceeds the maximum of %d: %w", len(rules), MaxStates, ErrTooManyStates)
 	}
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -164,3 +165,58 @@ func TestRulesSerialisation(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expected, actual)
 }
+
+func TestUnmarshalMaxStates(t *testing.T) {
+	defer func(max int) { MaxStates = max }(MaxStates)
+	MaxStates = 10
+	lexer := func(states int) []byte {
+		buf := &bytes.Buffer{}
+		buf.WriteString(`<lexer><config><name>Big</name></config><rules>`)
+		buf.WriteString(`<state name="root"><rule pattern="."><token type="Text"/></rule></state>`)
+		for i := 1; i < states; i++ {
+			fmt.Fprintf(buf, `<state name="s%d"><rule pattern="."><token type="Text"/></rule></state>`, i)
+		}
+		buf.WriteString(`</rules></lexer>`)
+		return buf.Bytes()
+	}
+	_, err := Unmarshal(lexer(10))
+	require.NoError(t, err)
+	_, err = Unmarshal(lexer(11))
+	require.ErrorIs(t, err, ErrTooManyStates)
+
+	// Decoding stops as soon as the limit is exceeded, before reaching the
+	// invalid state that follows.
+	invalid := bytes.TrimSuffix(lexer(11), []byte(`</rules></lexer>`))
+	invalid = append(invalid, `<state name="bad"><rule pattern="."><unknown/></rule></state></rules></lexer>`...)
+	_, err = Unmarshal(invalid)
+	require.ErrorIs(t, err, ErrTooManyStates)
+
+	// NewXMLLexer fails on construction.
+	fsys := fstest.MapFS{
+		"ok.xml":  {Data: lexer(10)},
+		"big.xml": {Data: lexer(11)},
+	}
+	_, err = NewXMLLexer(fsys, "ok.xml")
+	require.NoError(t, err)
+	_, err = NewXMLLexer(fsys, "big.xml")
+	require.ErrorIs(t, err, ErrTooManyStates)
+	// Whereas a lazy lexer fails on first use.
+	lazy, err := NewLazyXMLLexer(fsys, "big.xml")
+	require.NoError(t, err)
+	_, err = lazy.Tokenise(nil, "x")
+	require.ErrorIs(t, err, ErrTooManyStates)
+
+	// Compiled lexers are also checked.
+	big, err := NewLexer(&Config{Name: "Big"}, func() Rules {
+		rules := Rules{"root": {{".", Text, nil}}}
+		for i := 1; i < 11; i++ {
+			rules[fmt.Sprintf("s%d", i)] = []Rule{{".", Text, nil}}
+		}
+		return rules
+	})
+	require.NoError(t, err)
+	data, err := Compile(big)
+	require.NoError(t, err)
+	_, err = LoadCompiled(data)
+	require.ErrorIs(t, err, ErrTooManyStates)
+}
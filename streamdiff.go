@@ -0,0 +1,107 @@
+package chroma
+
+import (
+	"sort"
+)
+
+// SpanChangeKind is the kind of a SpanChange.
+type SpanChangeKind int
+
+// Kinds of SpanChange.
+const (
+	// SpanInserted text exists only in the new stream.
+	SpanInserted SpanChangeKind = iota
+	// SpanRemoved text exists only in the old stream.
+	SpanRemoved
+	// SpanRetyped text is unchanged but its TokenType differs.
+	SpanRetyped
+)
+
+func (k SpanChangeKind) String() string {
+	switch k {
+	case SpanInserted:
+		return "Inserted"
+	case SpanRemoved:
+		return "Removed"
+	default:
+		return "Retyped"
+	}
+}
+
+// A SpanChange describes a change between two token streams.
+//
+// Old and New are the [start, end) byte ranges of the change in the text of
+// the old and new streams respectively. The Old range of an insertion and the
+// New range of a removal are empty, marking where the change occurred.
+type SpanChange struct {
+	Kind SpanChangeKind
+	Old  [2]int
+	New  [2]int
+}
+
+// DiffTokenStreams describes the changes between the old and new token streams
+// of a re-lexed text, eg. so that a live editor need only repaint changed spans.
+//
+// The edited region is found by trimming the text common to the start and end
+// of both streams, and is reported as a removal and/or insertion. Text outside
+// of it whose type differs between the streams, eg. because an edit opened a
+// string, is reported as retyped. Changes are ordered by offset.
+func DiffTokenStreams(old, new []Token) []SpanChange {
+	oldText, newText := Stringify(old...), Stringify(new...)
+	prefix := 0
+	for prefix < len(oldText) && prefix < len(newText) && oldText[prefix] == newText[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldText)-prefix && suffix < len(newText)-prefix &&
+		oldText[len(oldText)-1-suffix] == newText[len(newText)-1-suffix] {
+		suffix++
+	}
+	oldEnd, newEnd := len(oldText)-suffix, len(newText)-suffix
+
+	oldEnds, newEnds := tokenEnds(old), tokenEnds(new)
+	changes := retypedSpans(nil, old, oldEnds, new, newEnds, 0, prefix, 0)
+	if oldEnd > prefix {
+		changes = append(changes, SpanChange{Kind: SpanRemoved, Old: [2]int{prefix, oldEnd}, New: [2]int{prefix, prefix}})
+	}
+	if newEnd > prefix {
+		changes = append(changes, SpanChange{Kind: SpanInserted, Old: [2]int{oldEnd, oldEnd}, New: [2]int{prefix, newEnd}})
+	}
+	return retypedSpans(changes, old, oldEnds, new, newEnds, oldEnd, len(oldText), newEnd-oldEnd)
+}
+
+// tokenEnds returns the byte offset of the end of each token.
+func tokenEnds(tokens []Token) []int {
+	ends := make([]int, len(tokens))
+	offset := 0
+	for i, t := range tokens {
+		offset += len(t.Value)
+		ends[i] = offset
+	}
+	return ends
+}
+
+// retypedSpans appends changes for the text in [start, end) of old, which is
+// at an offset of shift in new, where the types of the two streams differ.
+func retypedSpans(changes []SpanChange, old []Token, oldEnds []int, new []Token, newEnds []int, start, end, shift int) []SpanChange {
+	for pos := start; pos < end; {
+		oi := sort.Search(len(oldEnds), func(i int) bool { return oldEnds[i] > pos })
+		ni := sort.Search(len(newEnds), func(i int) bool { return newEnds[i] > pos+shift })
+		next := end
+		if oldEnds[oi] < next {
+			next = oldEnds[oi]
+		}
+		if newEnds[ni]-shift < next {
+			next = newEnds[ni] - shift
+		}
+		if old[oi].Type != new[ni].Type {
+			if n := len(changes); n > 0 && changes[n-1].Kind == SpanRetyped && changes[n-1].Old[1] == pos {
+				changes[n-1].Old[1], changes[n-1].New[1] = next, next+shift
+			} else {
+				changes = append(changes, SpanChange{Kind: SpanRetyped, Old: [2]int{pos, next}, New: [2]int{pos + shift, next + shift}})
+			}
+		}
+		pos = next
+	}
+	return changes
+}
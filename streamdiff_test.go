@@ -0,0 +1,37 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTokenStreams(t *testing.T) {
+	old := []Token{{Name, "foo"}, {Text, " "}, {Number, "12"}, {Text, " "}, {Name, "bar"}}
+	assert.Empty(t, DiffTokenStreams(old, old))
+
+	// Appending a digit to a number.
+	new := []Token{{Name, "foo"}, {Text, " "}, {Number, "123"}, {Text, " "}, {Name, "bar"}}
+	assert.Equal(t, []SpanChange{
+		{Kind: SpanInserted, Old: [2]int{6, 6}, New: [2]int{6, 7}},
+	}, DiffTokenStreams(old, new))
+
+	// Opening a string retypes the rest of the text.
+	new = []Token{{Name, "foo"}, {Text, " "}, {String, `"12 bar`}}
+	assert.Equal(t, []SpanChange{
+		{Kind: SpanInserted, Old: [2]int{4, 4}, New: [2]int{4, 5}},
+		{Kind: SpanRetyped, Old: [2]int{4, 10}, New: [2]int{5, 11}},
+	}, DiffTokenStreams(old, new))
+
+	// Replacing a name.
+	new = []Token{{Keyword, "if"}, {Text, " "}, {Number, "12"}, {Text, " "}, {Name, "bar"}}
+	assert.Equal(t, []SpanChange{
+		{Kind: SpanRemoved, Old: [2]int{0, 3}, New: [2]int{0, 0}},
+		{Kind: SpanInserted, Old: [2]int{3, 3}, New: [2]int{0, 2}},
+	}, DiffTokenStreams(old, new))
+
+	// Deleting everything.
+	assert.Equal(t, []SpanChange{
+		{Kind: SpanRemoved, Old: [2]int{0, 10}, New: [2]int{0, 0}},
+	}, DiffTokenStreams(old, nil))
+}
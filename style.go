@@ -140,9 +140,10 @@ func (s StyleEntry) IsZero() bool {
 //
 // Once built, a Style is immutable.
 type StyleBuilder struct {
-	entries map[TokenType]string
-	name    string
-	parent  *Style
+	entries   map[TokenType]string
+	name      string
+	parent    *Style
+	fallbacks FallbackResolver
 }
 
 func NewStyleBuilder(name string) *StyleBuilder {
@@ -175,11 +176,22 @@ func (s *StyleBuilder) AddEntry(ttype TokenType, entry StyleEntry) *StyleBuilder
 	return s
 }
 
+// Fallbacks sets the FallbackResolver used to resolve entries of the built
+// Style. If not set, the parent's resolver or DefaultFallbacks is used.
+func (s *StyleBuilder) Fallbacks(resolver FallbackResolver) *StyleBuilder {
+	s.fallbacks = resolver
+	return s
+}
+
 func (s *StyleBuilder) Build() (*Style, error) {
 	style := &Style{
-		Name:    s.name,
-		entries: map[TokenType]StyleEntry{},
-		parent:  s.parent,
+		Name:      s.name,
+		entries:   map[TokenType]StyleEntry{},
+		parent:    s.parent,
+		fallbacks: s.fallbacks,
+	}
+	if style.fallbacks == nil && s.parent != nil {
+		style.fallbacks = s.parent.fallbacks
 	}
 	for ttype, descriptor := range s.entries {
 		entry, err := ParseStyleEntry(descriptor)
@@ -212,9 +224,20 @@ func MustNewStyle(name string, entries StyleEntries) *Style {
 //
 // See http://pygments.org/docs/styles/ for details. Semantics are intended to be identical.
 type Style struct {
-	Name    string
-	entries map[TokenType]StyleEntry
-	parent  *Style
+	Name      string
+	entries   map[TokenType]StyleEntry
+	parent    *Style
+	fallbacks FallbackResolver
+}
+
+// A FallbackResolver returns the types a TokenType inherits unset style
+// attributes from, ordered from oldest to newest ancestor.
+type FallbackResolver func(ttype TokenType) []TokenType
+
+// DefaultFallbacks resolves a TokenType to its sub-category, then category,
+// then Text, and finally Background.
+func DefaultFallbacks(ttype TokenType) []TokenType {
+	return []TokenType{Background, Text, ttype.Category(), ttype.SubCategory()}
 }
 
 // Types that are styled.
@@ -240,9 +263,10 @@ func (s *Style) Types() []TokenType {
 // The builder can then be safely modified. This is a cheap operation.
 func (s *Style) Builder() *StyleBuilder {
 	return &StyleBuilder{
-		name:    s.Name,
-		entries: map[TokenType]string{},
-		parent:  s,
+		name:      s.Name,
+		entries:   map[TokenType]string{},
+		parent:    s,
+		fallbacks: s.fallbacks,
 	}
 }
 
@@ -255,12 +279,28 @@ func (s *Style) Has(ttype TokenType) bool {
 
 // Get a style entry. Will try sub-category or category if an exact match is not found, and
 // finally return the Background.
+//
+// The fallback chain may be customised with StyleBuilder.Fallbacks.
 func (s *Style) Get(ttype TokenType) StyleEntry {
-	return s.get(ttype).Inherit(
-		s.get(Background),
-		s.get(Text),
-		s.get(ttype.Category()),
-		s.get(ttype.SubCategory()))
+	if s.fallbacks == nil {
+		return s.get(ttype).Inherit(
+			s.get(Background),
+			s.get(Text),
+			s.get(ttype.Category()),
+			s.get(ttype.SubCategory()))
+	}
+	fallbacks := s.fallbacks(ttype)
+	ancestors := make([]StyleEntry, len(fallbacks))
+	for i, fallback := range fallbacks {
+		ancestors[i] = s.get(fallback)
+	}
+	return s.get(ttype).Inherit(ancestors...)
+}
+
+// StyleForToken returns the fully resolved StyleEntry a formatter will use for
+// token t.
+func (s *Style) StyleForToken(t Token) StyleEntry {
+	return s.Get(t.Type)
 }
 
 func (s *Style) get(ttype TokenType) StyleEntry {
@@ -288,13 +328,42 @@ func (s *Style) synthesise(ttype TokenType) StyleEntry {
 	case LineNumbers, LineNumbersTable:
 		return text
 
+	// If we don't have a cursor style, invert the default text and background colours.
+	case Cursor:
+		cursor := StyleEntry{Colour: bg.Background, Background: bg.Colour}
+		if !cursor.Background.IsSet() {
+			cursor.Background = bg.Background.BrightenOrDarken(1)
+		}
+		return cursor
+
 	default:
 		return StyleEntry{}
 	}
 }
 
+// synthesisedTypes are the types whose entries a Style derives from other
+// entries, such as Background, if they are not set explicitly.
+var synthesisedTypes = []TokenType{LineHighlight, LineNumbers, LineNumbersTable, Cursor}
+
+// SynthesisedTypes returns the token types whose entries a Style synthesises
+// from other entries if they are not set explicitly.
+func SynthesisedTypes() []TokenType {
+	return append([]TokenType(nil), synthesisedTypes...)
+}
+
+// IsSynthesised returns true if a Style synthesises the entry for ttype from
+// other entries when it is not set explicitly.
+func IsSynthesised(ttype TokenType) bool {
+	for _, tt := range synthesisedTypes {
+		if tt == ttype {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Style) synthesisable(ttype TokenType) bool {
-	return ttype == LineHighlight || ttype == LineNumbers || ttype == LineNumbersTable
+	return IsSynthesised(ttype)
 }
 
 // ParseStyleEntry parses a Pygments style entry.
@@ -47,6 +47,8 @@ func TestSynthesisedStyleEntries(t *testing.T) {
 	assert.Equal(t, "bg:#e5e5e5", style.Get(LineHighlight).String())
 	assert.Equal(t, "#7f7f7f bg:#ffffff", style.Get(LineNumbers).String())
 	assert.Equal(t, "#7f7f7f bg:#ffffff", style.Get(LineNumbersTable).String())
+	assert.True(t, style.Has(Cursor))
+	assert.Equal(t, "#ffffff bg:#000000", style.Get(Cursor).String())
 }
 
 func TestSynthesisedStyleClone(t *testing.T) {
@@ -63,3 +65,44 @@ func TestSynthesisedStyleClone(t *testing.T) {
 	assert.Equal(t, "bg:#ffffff", style.Get(LineHighlight).String())
 	assert.Equal(t, "bg:#fffff1", style.Get(LineNumbers).String())
 }
+
+func TestStyleForToken(t *testing.T) {
+	style, err := NewStyle("test", StyleEntries{
+		Background:   "#000 bg:#fff",
+		Name:         "bold #f00",
+		NameFunction: "#0f0",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bold #00ff00 bg:#ffffff", style.StyleForToken(Token{NameFunction, "main"}).String())
+	assert.Equal(t, "bold #ff0000 bg:#ffffff", style.StyleForToken(Token{NameVariable, "x"}).String())
+	assert.Equal(t, "#000000 bg:#ffffff", style.StyleForToken(Token{Keyword, "func"}).String())
+}
+
+func TestStyleFallbacks(t *testing.T) {
+	entries := StyleEntries{
+		Background: "#000 bg:#fff",
+		Name:       "#f00",
+		Comment:    "italic",
+	}
+	style, err := NewStyle("test", entries)
+	assert.NoError(t, err)
+	assert.Equal(t, "#ff0000 bg:#ffffff", style.Get(NameVariable).String())
+
+	// Make Name types fall back to Comment before their own category.
+	resolver := func(ttype TokenType) []TokenType {
+		fallbacks := DefaultFallbacks(ttype)
+		if ttype.InCategory(Name) {
+			fallbacks = append([]TokenType{Background, Text, Comment}, fallbacks[2:]...)
+		}
+		return fallbacks
+	}
+	style, err = NewStyleBuilder("test").AddAll(entries).Fallbacks(resolver).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "italic #ff0000 bg:#ffffff", style.Get(NameVariable).String())
+	assert.Equal(t, "#000000 bg:#ffffff", style.Get(Keyword).String())
+
+	// Derived styles keep the resolver.
+	clone, err := style.Builder().Add(Comment, "bold").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "bold #ff0000 bg:#ffffff", clone.Get(NameVariable).String())
+}
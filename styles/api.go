@@ -1,6 +1,7 @@
 package styles
 
 import (
+	"regexp"
 	"sort"
 
 	"github.com/alecthomas/chroma/v2"
@@ -35,3 +36,26 @@ func Get(name string) *chroma.Style {
 	}
 	return Fallback
 }
+
+var (
+	styleFieldRe = regexp.MustCompile(`\S+`)
+	bareHexRe    = regexp.MustCompile(`^(bg:|border:)?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
+// FromMap builds a Style from a map of token types to style entries.
+//
+// Entries use the same syntax as chroma.StyleEntries, except that colours may
+// also be given as bare hex values without a leading "#", eg. "ff0000" or
+// "bold bg:333". The resulting Style is not registered.
+func FromMap(name string, entries map[chroma.TokenType]string) (*chroma.Style, error) {
+	normalised := chroma.StyleEntries{}
+	for ttype, entry := range entries {
+		normalised[ttype] = styleFieldRe.ReplaceAllStringFunc(entry, func(part string) string {
+			if m := bareHexRe.FindStringSubmatch(part); m != nil {
+				return m[1] + "#" + m[2]
+			}
+			return part
+		})
+	}
+	return chroma.NewStyle(name, normalised)
+}
@@ -0,0 +1,30 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestFromMap(t *testing.T) {
+	style, err := FromMap("test", map[chroma.TokenType]string{
+		chroma.Background: "bg:000000 #ffffff",
+		chroma.Keyword:    "bold ff0000",
+		chroma.Comment:    "italic #888",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "test", style.Name)
+	require.Equal(t, chroma.MustParseColour("#ff0000"), style.Get(chroma.Keyword).Colour)
+	require.Equal(t, chroma.Yes, style.Get(chroma.Keyword).Bold)
+	require.Equal(t, chroma.MustParseColour("#888888"), style.Get(chroma.CommentSingle).Colour)
+	require.Equal(t, chroma.MustParseColour("#000000"), style.Get(chroma.Background).Background)
+	_, ok := Registry["test"]
+	require.False(t, ok)
+
+	_, err = FromMap("bad", map[chroma.TokenType]string{chroma.Keyword: "#zzzzzz"})
+	require.Error(t, err)
+	_, err = FromMap("bad", map[chroma.TokenType]string{chroma.Keyword: "blink"})
+	require.Error(t, err)
+}
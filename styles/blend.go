@@ -0,0 +1,36 @@
+package styles
+
+import (
+	"math"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Blend returns a style derived from base in which each colour of the entries
+// styled by tint is blended towards the corresponding colour of tint by factor,
+// from 0 (base) to 1 (tint), eg. for generating dimmed or tinted themes.
+//
+// Types not styled by tint, and colours either style leaves unset, are
+// unchanged from base.
+func Blend(base, tint *chroma.Style, factor float64) (*chroma.Style, error) {
+	factor = math.Max(0, math.Min(1, factor))
+	builder := base.Builder()
+	for _, tt := range tint.Types() {
+		entry, tinted := base.Get(tt), tint.Get(tt)
+		entry.Colour = blendColour(entry.Colour, tinted.Colour, factor)
+		entry.Background = blendColour(entry.Background, tinted.Background, factor)
+		entry.Border = blendColour(entry.Border, tinted.Border, factor)
+		builder.AddEntry(tt, entry)
+	}
+	return builder.Build()
+}
+
+func blendColour(base, tint chroma.Colour, factor float64) chroma.Colour {
+	if !base.IsSet() || !tint.IsSet() {
+		return base
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*factor))
+	}
+	return chroma.NewColour(mix(base.Red(), tint.Red()), mix(base.Green(), tint.Green()), mix(base.Blue(), tint.Blue()))
+}
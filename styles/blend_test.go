@@ -0,0 +1,37 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestBlend(t *testing.T) {
+	base := chroma.MustNewStyle("base", chroma.StyleEntries{
+		chroma.Background: "#000000 bg:#ffffff",
+		chroma.Keyword:    "bold #0000ff",
+		chroma.Comment:    "italic #888888",
+	})
+	tint := chroma.MustNewStyle("tint", chroma.StyleEntries{
+		chroma.Background: "bg:#000000",
+		chroma.Keyword:    "#ff0000",
+	})
+	blended, err := Blend(base, tint, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, "bold #800080 bg:#808080", blended.Get(chroma.Keyword).String())
+	// Comment is not styled by tint, but inherits the blended background.
+	assert.Equal(t, "italic #888888 bg:#808080", blended.Get(chroma.Comment).String())
+	assert.Equal(t, "#000000 bg:#808080", blended.Get(chroma.Background).String())
+
+	blended, err = Blend(base, tint, 0)
+	require.NoError(t, err)
+	assert.Equal(t, base.Get(chroma.Keyword), blended.Get(chroma.Keyword))
+	blended, err = Blend(base, tint, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "bold #ff0000 bg:#000000", blended.Get(chroma.Keyword).String())
+	// The base style is unchanged.
+	assert.Equal(t, "bold #0000ff bg:#ffffff", base.Get(chroma.Keyword).String())
+}
@@ -0,0 +1,34 @@
+package styles
+
+import (
+	"sort"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// CoverageReport lexes sample with lexer and returns the token types emitted
+// that style does not style, either directly or via their sub-category or
+// category, and so fall back all the way to the default Text style. Text types
+// themselves are not reported.
+//
+// Types are returned in ascending order.
+func CoverageReport(style *chroma.Style, lexer chroma.Lexer, sample string) ([]chroma.TokenType, error) {
+	it, err := lexer.Tokenise(nil, sample)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[chroma.TokenType]bool{}
+	out := []chroma.TokenType{}
+	for t := it(); t != chroma.EOF; t = it() {
+		if seen[t.Type] {
+			continue
+		}
+		seen[t.Type] = true
+		if t.Type.InCategory(chroma.Text) || style.Has(t.Type) || style.Has(t.Type.SubCategory()) || style.Has(t.Type.Category()) {
+			continue
+		}
+		out = append(out, t.Type)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
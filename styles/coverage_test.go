@@ -0,0 +1,30 @@
+package styles
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+func TestCoverageReport(t *testing.T) {
+	sample, err := os.ReadFile("testdata/coverage.go")
+	require.NoError(t, err)
+	style := chroma.MustNewStyle("partial", chroma.StyleEntries{
+		chroma.Background: "#000000 bg:#ffffff",
+		chroma.Keyword:    "bold #0000ff",
+		chroma.Name:       "#00ff00",
+		chroma.String:     "#ff0000",
+	})
+	missing, err := CoverageReport(style, lexers.Get("go"), string(sample))
+	require.NoError(t, err)
+	assert.Equal(t, []chroma.TokenType{chroma.LiteralNumberInteger, chroma.Operator, chroma.Punctuation, chroma.CommentSingle}, missing)
+
+	missing, err = CoverageReport(Get("monokai"), lexers.Get("go"), string(sample))
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
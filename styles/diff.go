@@ -0,0 +1,28 @@
+package styles
+
+import (
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Diff returns the token types whose resolved style entries differ between a
+// and b, mapped to their old (a) and new (b) values.
+//
+// Entries are compared after inheritance, so a change to a parent category is
+// reported for every styled sub-type it affects.
+func Diff(a, b *chroma.Style) map[chroma.TokenType][2]chroma.StyleEntry {
+	types := map[chroma.TokenType]bool{}
+	for _, tt := range a.Types() {
+		types[tt] = true
+	}
+	for _, tt := range b.Types() {
+		types[tt] = true
+	}
+	out := map[chroma.TokenType][2]chroma.StyleEntry{}
+	for tt := range types {
+		old, updated := a.Get(tt), b.Get(tt)
+		if old != updated {
+			out[tt] = [2]chroma.StyleEntry{old, updated}
+		}
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestDiff(t *testing.T) {
+	a := chroma.MustNewStyle("a", chroma.StyleEntries{
+		chroma.Background: "bg:#000000 #ffffff",
+		chroma.Keyword:    "bold #ff0000",
+		chroma.Comment:    "italic #888888",
+	})
+	b := chroma.MustNewStyle("b", chroma.StyleEntries{
+		chroma.Background: "bg:#000000 #ffffff",
+		chroma.Keyword:    "bold #00ff00",
+		chroma.Comment:    "italic #888888",
+		chroma.String:     "#0000ff",
+	})
+	diff := Diff(a, b)
+	require.Equal(t, map[chroma.TokenType][2]chroma.StyleEntry{
+		chroma.Keyword: {a.Get(chroma.Keyword), b.Get(chroma.Keyword)},
+		chroma.String:  {a.Get(chroma.String), b.Get(chroma.String)},
+	}, diff)
+	require.Equal(t, chroma.MustParseColour("#ff0000"), diff[chroma.Keyword][0].Colour)
+	require.Equal(t, chroma.MustParseColour("#00ff00"), diff[chroma.Keyword][1].Colour)
+	require.Empty(t, Diff(a, a))
+}
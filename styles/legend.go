@@ -0,0 +1,48 @@
+package styles
+
+import (
+	"github.com/alecthomas/chroma/v2"
+)
+
+// A LegendEntry describes the colour a Style uses for a category of tokens.
+type LegendEntry struct {
+	Category chroma.TokenType
+	Colour   chroma.Colour
+	// Sample text representative of the category, for rendering the legend.
+	Sample string
+}
+
+// Legend categories, in display order, with their sample text.
+var legendCategories = []struct {
+	category chroma.TokenType
+	sample   string
+}{
+	{chroma.Keyword, "func"},
+	{chroma.Name, "name"},
+	{chroma.NameFunction, "main"},
+	{chroma.NameBuiltin, "len"},
+	{chroma.LiteralString, `"string"`},
+	{chroma.LiteralNumber, "42"},
+	{chroma.Operator, "+"},
+	{chroma.Punctuation, "{}"},
+	{chroma.Comment, "// comment"},
+	{chroma.GenericInserted, "+added"},
+	{chroma.GenericDeleted, "-removed"},
+	{chroma.Error, "error"},
+	{chroma.Text, "text"},
+}
+
+// Legend returns the resolved colours s uses for the major token categories,
+// eg. for documenting a theme's palette. Each entry includes sample text that
+// can be tokenised as its Category and rendered with any formatter.
+func Legend(s *chroma.Style) []LegendEntry {
+	out := make([]LegendEntry, 0, len(legendCategories))
+	for _, c := range legendCategories {
+		out = append(out, LegendEntry{
+			Category: c.category,
+			Colour:   s.Get(c.category).Colour,
+			Sample:   c.sample,
+		})
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestLegend(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background:    "#111111 bg:#ffffff",
+		chroma.Keyword:       "bold #0000ff",
+		chroma.LiteralString: "#00ff00",
+		chroma.Comment:       "italic #888888",
+	})
+	legend := Legend(style)
+	categories := []chroma.TokenType{}
+	for _, entry := range legend {
+		categories = append(categories, entry.Category)
+		require.NotEmpty(t, entry.Sample)
+	}
+	require.Equal(t, []chroma.TokenType{
+		chroma.Keyword, chroma.Name, chroma.NameFunction, chroma.NameBuiltin,
+		chroma.LiteralString, chroma.LiteralNumber, chroma.Operator, chroma.Punctuation,
+		chroma.Comment, chroma.GenericInserted, chroma.GenericDeleted, chroma.Error, chroma.Text,
+	}, categories)
+	require.Equal(t, LegendEntry{Category: chroma.Keyword, Colour: chroma.MustParseColour("#0000ff"), Sample: "func"}, legend[0])
+	require.Equal(t, chroma.MustParseColour("#00ff00"), legend[4].Colour)
+	require.Equal(t, chroma.MustParseColour("#111111"), legend[1].Colour, "unstyled categories inherit the text colour")
+
+}
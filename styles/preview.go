@@ -0,0 +1,35 @@
+package styles
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// PreviewAll renders sample with each registered style, in the order
+// returned by Names, eg. for a style picker. Each rendering is preceded by a
+// line containing the name of the style.
+//
+// The lexer for sample is determined by lexers.Analyse, falling back to
+// lexers.Fallback.
+func PreviewAll(w io.Writer, sample string, formatter chroma.Formatter) error {
+	lexer := lexers.Analyse(sample)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	tokens, err := chroma.Tokenise(chroma.Coalesce(lexer), nil, sample)
+	if err != nil {
+		return err
+	}
+	for _, name := range Names() {
+		if _, err := fmt.Fprintf(w, "%s\n", name); err != nil {
+			return err
+		}
+		if err := formatter.Format(w, Registry[name], chroma.Literator(tokens...)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
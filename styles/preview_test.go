@@ -0,0 +1,21 @@
+package styles
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+)
+
+func TestPreviewAll(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := PreviewAll(buf, "package main\n\nfunc main() {}\n", formatters.TTY16m)
+	require.NoError(t, err)
+	out := buf.Bytes()
+	for _, name := range Names() {
+		require.True(t, bytes.Contains(out, []byte(name+"\n")), name)
+	}
+	require.Equal(t, len(Names()), bytes.Count(out, []byte("func")))
+}
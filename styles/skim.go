@@ -0,0 +1,50 @@
+package styles
+
+import (
+	"github.com/alecthomas/chroma/v2"
+)
+
+// SkimTypes are the token types, and their sub-types, left styled by SkimMode
+// by default.
+var SkimTypes = []chroma.TokenType{chroma.Keyword, chroma.NameFunction, chroma.NameClass}
+
+// SkimMode returns a style derived from style for skimming code, in which only
+// tokens of the keep types or their sub-types are styled as in style, while
+// all others are dimmed towards the background colour, without font
+// attributes. If no types are given, SkimTypes is used.
+func SkimMode(style *chroma.Style, keep ...chroma.TokenType) (*chroma.Style, error) {
+	if len(keep) == 0 {
+		keep = SkimTypes
+	}
+	kept := map[chroma.TokenType]bool{}
+	for _, tt := range keep {
+		kept[tt] = true
+	}
+	background := style.Get(chroma.Background).Background
+	builder := style.Builder()
+	for tt := range chroma.StandardTypes {
+		if tt <= chroma.EOFType {
+			continue
+		}
+		entry := style.Get(tt)
+		if isKept(kept, tt) {
+			// Pin the entry, as the types it would inherit from may be dimmed.
+			builder.AddEntry(tt, entry)
+			continue
+		}
+		builder.AddEntry(tt, chroma.StyleEntry{
+			Colour:     blendColour(entry.Colour, background, 0.5),
+			Background: entry.Background,
+		})
+	}
+	return builder.Build()
+}
+
+func isKept(kept map[chroma.TokenType]bool, tt chroma.TokenType) bool {
+	for ; tt != 0; tt = tt.Parent() {
+		if kept[tt] {
+			return true
+		}
+	}
+	return false
+}
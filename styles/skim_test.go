@@ -0,0 +1,34 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestSkimMode(t *testing.T) {
+	style := chroma.MustNewStyle("test", chroma.StyleEntries{
+		chroma.Background:   "#000000 bg:#ffffff",
+		chroma.Keyword:      "bold #0000ff",
+		chroma.NameFunction: "#00ff00",
+		chroma.Comment:      "italic #888888",
+		chroma.String:       "#ff0000",
+	})
+	skimmed, err := SkimMode(style)
+	require.NoError(t, err)
+	assert.Equal(t, "bold #0000ff bg:#ffffff", skimmed.Get(chroma.KeywordDeclaration).String())
+	assert.Equal(t, "#00ff00 bg:#ffffff", skimmed.Get(chroma.NameFunction).String())
+	assert.Equal(t, "#000000 bg:#ffffff", skimmed.Get(chroma.NameClass).String())
+	assert.Equal(t, "#ff8080 bg:#ffffff", skimmed.Get(chroma.StringDouble).String())
+	assert.Equal(t, "#c4c4c4 bg:#ffffff", skimmed.Get(chroma.Comment).String())
+	assert.Equal(t, "#808080 bg:#ffffff", skimmed.Get(chroma.NameVariable).String())
+
+	// Only strings are kept.
+	skimmed, err = SkimMode(style, chroma.String)
+	require.NoError(t, err)
+	assert.Equal(t, "#ff0000 bg:#ffffff", skimmed.Get(chroma.StringDouble).String())
+	assert.Equal(t, "#8080ff bg:#ffffff", skimmed.Get(chroma.Keyword).String())
+}
@@ -0,0 +1,41 @@
+package styles
+
+import (
+	"github.com/alecthomas/chroma/v2"
+)
+
+// SplitDecorations splits s into a style carrying only its colours and a style
+// carrying only its bold, italic and underline decorations, eg. for rendering
+// them in separate passes.
+//
+// Both styles contain resolved entries for every type styled by s, so
+// combining the colours of one with the decorations of the other reproduces s.
+func SplitDecorations(s *chroma.Style) (colorStyle, decorStyle *chroma.Style, err error) {
+	colours := chroma.NewStyleBuilder(s.Name)
+	decorations := chroma.NewStyleBuilder(s.Name)
+	for _, tt := range append(s.Types(), chroma.SynthesisedTypes()...) {
+		entry := s.Get(tt)
+		colours.AddEntry(tt, chroma.StyleEntry{
+			Colour:     entry.Colour,
+			Background: entry.Background,
+			Border:     entry.Border,
+			NoInherit:  entry.NoInherit,
+		})
+		decorations.AddEntry(tt, chroma.StyleEntry{
+			Bold:      entry.Bold,
+			Italic:    entry.Italic,
+			Underline: entry.Underline,
+			// Prevent colours being synthesised for line numbers, highlights, etc.
+			NoInherit: entry.NoInherit || chroma.IsSynthesised(tt),
+		})
+	}
+	colorStyle, err = colours.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	decorStyle, err = decorations.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return colorStyle, decorStyle, nil
+}
@@ -0,0 +1,28 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func TestSplitDecorations(t *testing.T) {
+	for _, name := range []string{"monokai", "github", "swapoff"} {
+		style := Get(name)
+		colours, decorations, err := SplitDecorations(style)
+		require.NoError(t, err)
+		for tt := range chroma.StandardTypes {
+			colour, decoration := colours.Get(tt), decorations.Get(tt)
+			require.Equal(t, chroma.Pass, colour.Bold, "%s %s", name, tt)
+			require.Equal(t, chroma.Pass, colour.Italic, "%s %s", name, tt)
+			require.Equal(t, chroma.Pass, colour.Underline, "%s %s", name, tt)
+			require.False(t, decoration.Colour.IsSet() || decoration.Background.IsSet() || decoration.Border.IsSet(), "%s %s", name, tt)
+
+			combined := colour
+			combined.Bold, combined.Italic, combined.Underline = decoration.Bold, decoration.Italic, decoration.Underline
+			require.Equal(t, style.Get(tt), combined, "%s %s", name, tt)
+		}
+	}
+}
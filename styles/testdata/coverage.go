@@ -0,0 +1,9 @@
+package main
+
+import "fmt"
+
+// main prints a greeting.
+func main() {
+	name := "world"
+	fmt.Printf("hello %s %d\n", name, 42)
+}
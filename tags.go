@@ -0,0 +1,65 @@
+package chroma
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// TagComments splits Comment tokens around any of tags, eg. "TODO" or
+// "FIXME", retyping the tags as CommentSpecial so that they stand out.
+//
+// Tags are matched case-insensitively and only as whole words: a tag that
+// begins or ends with a word character, as matched by \w, is not matched
+// where that end adjoins another word character. Tags may also contain
+// punctuation, eg. "@todo" or "XXX!". Other tokens are passed through
+// unchanged.
+func TagComments(it Iterator, tags ...string) Iterator {
+	if len(tags) == 0 {
+		return it
+	}
+	patterns := make([]string, len(tags))
+	for i, tag := range tags {
+		patterns[i] = tagPattern(tag)
+	}
+	re := regexp.MustCompile(`(?i)(?:` + strings.Join(patterns, `|`) + `)`)
+	var queue []Token
+	return func() Token {
+		for len(queue) == 0 {
+			t := it()
+			if t == EOF {
+				return EOF
+			}
+			queue = tagComment(re, t)
+		}
+		t := queue[0]
+		queue = queue[1:]
+		return t
+	}
+}
+
+func tagComment(re *regexp.Regexp, t Token) []Token {
+	if !t.Type.InSubCategory(Comment) || t.Type == CommentSpecial || t.Type == CommentLink {
+		return []Token{t}
+	}
+	return splitMatches(t, re.FindAllStringIndex(t.Value, -1), CommentSpecial)
+}
+
+// tagPattern matches tag where it is not part of a longer word. A word
+// boundary only applies at an end of the tag that is itself a word character,
+// as \b never matches between eg. "@" and the space before it.
+func tagPattern(tag string) string {
+	pattern := regexp.QuoteMeta(tag)
+	if first, _ := utf8.DecodeRuneInString(tag); isWordRune(first) {
+		pattern = `\b` + pattern
+	}
+	if last, _ := utf8.DecodeLastRuneInString(tag); isWordRune(last) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// isWordRune reports whether r is a word character as matched by \w.
+func isWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,41 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagComments(t *testing.T) {
+	it := TagComments(Literator(
+		Token{CommentSingle, "// todo: fix this, FIXME later; not todos or xTODO."},
+		Token{Name, "TODO"},
+		Token{CommentMultiline, "/* Note */"},
+	), "TODO", "FIXME", "NOTE")
+	expected := []Token{
+		{CommentSingle, "// "},
+		{CommentSpecial, "todo"},
+		{CommentSingle, ": fix this, "},
+		{CommentSpecial, "FIXME"},
+		{CommentSingle, " later; not todos or xTODO."},
+		{Name, "TODO"},
+		{CommentMultiline, "/* "},
+		{CommentSpecial, "Note"},
+		{CommentMultiline, " */"},
+	}
+	assert.Equal(t, expected, it.Tokens())
+}
+
+func TestTagCommentsPunctuation(t *testing.T) {
+	it := TagComments(Literator(
+		Token{CommentSingle, "# @todo XXX! @todos"},
+	), "@todo", "XXX!")
+	expected := []Token{
+		{CommentSingle, "# "},
+		{CommentSpecial, "@todo"},
+		{CommentSingle, " "},
+		{CommentSpecial, "XXX!"},
+		{CommentSingle, " @todos"},
+	}
+	assert.Equal(t, expected, it.Tokens())
+}
@@ -0,0 +1,57 @@
+package chroma
+
+// A TokeniserFunc tokenises text by any means, eg. a hand-written scanner or
+// a binding to an external parser such as tree-sitter, rather than with the
+// regular expressions of a RegexLexer.
+//
+// The returned Iterator must produce tokens whose values concatenate to text,
+// followed by EOF.
+type TokeniserFunc func(options *TokeniseOptions, text string) (Iterator, error)
+
+// NewCustomLexer adapts tokenise to the Lexer interface, so that it can be
+// registered in a LexerRegistry and used with any Formatter, eg.
+//
+//	lexer := chroma.NewCustomLexer(&chroma.Config{Name: "INI"}, func(options *chroma.TokeniseOptions, text string) (chroma.Iterator, error) {
+//		return chroma.Literator(scanINI(text)...), nil
+//	})
+//
+// Unlike a RegexLexer, a custom lexer does not implement
+// TokeniserWithOriginalLen, so TokeniseWithOriginalLen reports token lengths
+// unchanged. A custom lexer scores 0 in AnalyseText unless an analyser is set
+// with SetAnalyser.
+func NewCustomLexer(config *Config, tokenise TokeniserFunc) Lexer {
+	if config == nil {
+		config = &Config{}
+	}
+	return &customLexer{config: config, tokenise: tokenise}
+}
+
+type customLexer struct {
+	config   *Config
+	tokenise TokeniserFunc
+	registry *LexerRegistry
+	analyser func(text string) float32
+}
+
+func (c *customLexer) Config() *Config { return c.config }
+
+func (c *customLexer) Tokenise(options *TokeniseOptions, text string) (Iterator, error) {
+	return c.tokenise(options, text)
+}
+
+func (c *customLexer) SetRegistry(registry *LexerRegistry) Lexer {
+	c.registry = registry
+	return c
+}
+
+func (c *customLexer) SetAnalyser(analyser func(text string) float32) Lexer {
+	c.analyser = analyser
+	return c
+}
+
+func (c *customLexer) AnalyseText(text string) float32 {
+	if c.analyser == nil {
+		return 0
+	}
+	return c.analyser(text)
+}
@@ -20,6 +20,7 @@ func _() {
 	_ = x[Error - -10]
 	_ = x[Other - -11]
 	_ = x[None - -12]
+	_ = x[Cursor - -13]
 	_ = x[EOFType-0]
 	_ = x[Keyword-1000]
 	_ = x[KeywordConstant-1001]
@@ -73,6 +74,7 @@ func _() {
 	_ = x[LiteralStringRegex-3114]
 	_ = x[LiteralStringSingle-3115]
 	_ = x[LiteralStringSymbol-3116]
+	_ = x[LiteralStringLink-3117]
 	_ = x[LiteralNumber-3200]
 	_ = x[LiteralNumberBin-3201]
 	_ = x[LiteralNumberFloat-3202]
@@ -88,6 +90,7 @@ func _() {
 	_ = x[CommentMultiline-6002]
 	_ = x[CommentSingle-6003]
 	_ = x[CommentSpecial-6004]
+	_ = x[CommentLink-6005]
 	_ = x[CommentPreproc-6100]
 	_ = x[CommentPreprocFile-6101]
 	_ = x[Generic-7000]
@@ -106,109 +109,114 @@ func _() {
 	_ = x[TextWhitespace-8001]
 	_ = x[TextSymbol-8002]
 	_ = x[TextPunctuation-8003]
+	_ = x[TextParagraph-8004]
 }
 
-const _TokenType_name = "NoneOtherErrorCodeLineLineTableTDLineTableLineHighlightLineNumbersTableLineNumbersLinePreWrapperBackgroundEOFTypeKeywordKeywordConstantKeywordDeclarationKeywordNamespaceKeywordPseudoKeywordReservedKeywordTypeNameNameAttributeNameBuiltinNameBuiltinPseudoNameClassNameConstantNameDecoratorNameEntityNameExceptionNameFunctionNameFunctionMagicNameKeywordNameLabelNameNamespaceNameOperatorNameOtherNamePseudoNamePropertyNameTagNameVariableNameVariableAnonymousNameVariableClassNameVariableGlobalNameVariableInstanceNameVariableMagicLiteralLiteralDateLiteralOtherLiteralStringLiteralStringAffixLiteralStringAtomLiteralStringBacktickLiteralStringBooleanLiteralStringCharLiteralStringDelimiterLiteralStringDocLiteralStringDoubleLiteralStringEscapeLiteralStringHeredocLiteralStringInterpolLiteralStringNameLiteralStringOtherLiteralStringRegexLiteralStringSingleLiteralStringSymbolLiteralNumberLiteralNumberBinLiteralNumberFloatLiteralNumberHexLiteralNumberIntegerLiteralNumberIntegerLongLiteralNumberOctOperatorOperatorWordPunctuationCommentCommentHashbangCommentMultilineCommentSingleCommentSpecialCommentPreprocCommentPreprocFileGenericGenericDeletedGenericEmphGenericErrorGenericHeadingGenericInsertedGenericOutputGenericPromptGenericStrongGenericSubheadingGenericTracebackGenericUnderlineTextTextWhitespaceTextSymbolTextPunctuation"
+const _TokenType_name = "CursorNoneOtherErrorCodeLineLineTableTDLineTableLineHighlightLineNumbersTableLineNumbersLinePreWrapperBackgroundEOFTypeKeywordKeywordConstantKeywordDeclarationKeywordNamespaceKeywordPseudoKeywordReservedKeywordTypeNameNameAttributeNameBuiltinNameBuiltinPseudoNameClassNameConstantNameDecoratorNameEntityNameExceptionNameFunctionNameFunctionMagicNameKeywordNameLabelNameNamespaceNameOperatorNameOtherNamePseudoNamePropertyNameTagNameVariableNameVariableAnonymousNameVariableClassNameVariableGlobalNameVariableInstanceNameVariableMagicLiteralLiteralDateLiteralOtherLiteralStringLiteralStringAffixLiteralStringAtomLiteralStringBacktickLiteralStringBooleanLiteralStringCharLiteralStringDelimiterLiteralStringDocLiteralStringDoubleLiteralStringEscapeLiteralStringHeredocLiteralStringInterpolLiteralStringNameLiteralStringOtherLiteralStringRegexLiteralStringSingleLiteralStringSymbolLiteralStringLinkLiteralNumberLiteralNumberBinLiteralNumberFloatLiteralNumberHexLiteralNumberIntegerLiteralNumberIntegerLongLiteralNumberOctOperatorOperatorWordPunctuationCommentCommentHashbangCommentMultilineCommentSingleCommentSpecialCommentLinkCommentPreprocCommentPreprocFileGenericGenericDeletedGenericEmphGenericErrorGenericHeadingGenericInsertedGenericOutputGenericPromptGenericStrongGenericSubheadingGenericTracebackGenericUnderlineTextTextWhitespaceTextSymbolTextPunctuationTextParagraph"
 
 var _TokenType_map = map[TokenType]string{
-	-12:  _TokenType_name[0:4],
-	-11:  _TokenType_name[4:9],
-	-10:  _TokenType_name[9:14],
-	-9:   _TokenType_name[14:22],
-	-8:   _TokenType_name[22:33],
-	-7:   _TokenType_name[33:42],
-	-6:   _TokenType_name[42:55],
-	-5:   _TokenType_name[55:71],
-	-4:   _TokenType_name[71:82],
-	-3:   _TokenType_name[82:86],
-	-2:   _TokenType_name[86:96],
-	-1:   _TokenType_name[96:106],
-	0:    _TokenType_name[106:113],
-	1000: _TokenType_name[113:120],
-	1001: _TokenType_name[120:135],
-	1002: _TokenType_name[135:153],
-	1003: _TokenType_name[153:169],
-	1004: _TokenType_name[169:182],
-	1005: _TokenType_name[182:197],
-	1006: _TokenType_name[197:208],
-	2000: _TokenType_name[208:212],
-	2001: _TokenType_name[212:225],
-	2002: _TokenType_name[225:236],
-	2003: _TokenType_name[236:253],
-	2004: _TokenType_name[253:262],
-	2005: _TokenType_name[262:274],
-	2006: _TokenType_name[274:287],
-	2007: _TokenType_name[287:297],
-	2008: _TokenType_name[297:310],
-	2009: _TokenType_name[310:322],
-	2010: _TokenType_name[322:339],
-	2011: _TokenType_name[339:350],
-	2012: _TokenType_name[350:359],
-	2013: _TokenType_name[359:372],
-	2014: _TokenType_name[372:384],
-	2015: _TokenType_name[384:393],
-	2016: _TokenType_name[393:403],
-	2017: _TokenType_name[403:415],
-	2018: _TokenType_name[415:422],
-	2019: _TokenType_name[422:434],
-	2020: _TokenType_name[434:455],
-	2021: _TokenType_name[455:472],
-	2022: _TokenType_name[472:490],
-	2023: _TokenType_name[490:510],
-	2024: _TokenType_name[510:527],
-	3000: _TokenType_name[527:534],
-	3001: _TokenType_name[534:545],
-	3002: _TokenType_name[545:557],
-	3100: _TokenType_name[557:570],
-	3101: _TokenType_name[570:588],
-	3102: _TokenType_name[588:605],
-	3103: _TokenType_name[605:626],
-	3104: _TokenType_name[626:646],
-	3105: _TokenType_name[646:663],
-	3106: _TokenType_name[663:685],
-	3107: _TokenType_name[685:701],
-	3108: _TokenType_name[701:720],
-	3109: _TokenType_name[720:739],
-	3110: _TokenType_name[739:759],
-	3111: _TokenType_name[759:780],
-	3112: _TokenType_name[780:797],
-	3113: _TokenType_name[797:815],
-	3114: _TokenType_name[815:833],
-	3115: _TokenType_name[833:852],
-	3116: _TokenType_name[852:871],
-	3200: _TokenType_name[871:884],
-	3201: _TokenType_name[884:900],
-	3202: _TokenType_name[900:918],
-	3203: _TokenType_name[918:934],
-	3204: _TokenType_name[934:954],
-	3205: _TokenType_name[954:978],
-	3206: _TokenType_name[978:994],
-	4000: _TokenType_name[994:1002],
-	4001: _TokenType_name[1002:1014],
-	5000: _TokenType_name[1014:1025],
-	6000: _TokenType_name[1025:1032],
-	6001: _TokenType_name[1032:1047],
-	6002: _TokenType_name[1047:1063],
-	6003: _TokenType_name[1063:1076],
-	6004: _TokenType_name[1076:1090],
-	6100: _TokenType_name[1090:1104],
-	6101: _TokenType_name[1104:1122],
-	7000: _TokenType_name[1122:1129],
-	7001: _TokenType_name[1129:1143],
-	7002: _TokenType_name[1143:1154],
-	7003: _TokenType_name[1154:1166],
-	7004: _TokenType_name[1166:1180],
-	7005: _TokenType_name[1180:1195],
-	7006: _TokenType_name[1195:1208],
-	7007: _TokenType_name[1208:1221],
-	7008: _TokenType_name[1221:1234],
-	7009: _TokenType_name[1234:1251],
-	7010: _TokenType_name[1251:1267],
-	7011: _TokenType_name[1267:1283],
-	8000: _TokenType_name[1283:1287],
-	8001: _TokenType_name[1287:1301],
-	8002: _TokenType_name[1301:1311],
-	8003: _TokenType_name[1311:1326],
+	-13:  _TokenType_name[0:6],
+	-12:  _TokenType_name[6:10],
+	-11:  _TokenType_name[10:15],
+	-10:  _TokenType_name[15:20],
+	-9:   _TokenType_name[20:28],
+	-8:   _TokenType_name[28:39],
+	-7:   _TokenType_name[39:48],
+	-6:   _TokenType_name[48:61],
+	-5:   _TokenType_name[61:77],
+	-4:   _TokenType_name[77:88],
+	-3:   _TokenType_name[88:92],
+	-2:   _TokenType_name[92:102],
+	-1:   _TokenType_name[102:112],
+	0:    _TokenType_name[112:119],
+	1000: _TokenType_name[119:126],
+	1001: _TokenType_name[126:141],
+	1002: _TokenType_name[141:159],
+	1003: _TokenType_name[159:175],
+	1004: _TokenType_name[175:188],
+	1005: _TokenType_name[188:203],
+	1006: _TokenType_name[203:214],
+	2000: _TokenType_name[214:218],
+	2001: _TokenType_name[218:231],
+	2002: _TokenType_name[231:242],
+	2003: _TokenType_name[242:259],
+	2004: _TokenType_name[259:268],
+	2005: _TokenType_name[268:280],
+	2006: _TokenType_name[280:293],
+	2007: _TokenType_name[293:303],
+	2008: _TokenType_name[303:316],
+	2009: _TokenType_name[316:328],
+	2010: _TokenType_name[328:345],
+	2011: _TokenType_name[345:356],
+	2012: _TokenType_name[356:365],
+	2013: _TokenType_name[365:378],
+	2014: _TokenType_name[378:390],
+	2015: _TokenType_name[390:399],
+	2016: _TokenType_name[399:409],
+	2017: _TokenType_name[409:421],
+	2018: _TokenType_name[421:428],
+	2019: _TokenType_name[428:440],
+	2020: _TokenType_name[440:461],
+	2021: _TokenType_name[461:478],
+	2022: _TokenType_name[478:496],
+	2023: _TokenType_name[496:516],
+	2024: _TokenType_name[516:533],
+	3000: _TokenType_name[533:540],
+	3001: _TokenType_name[540:551],
+	3002: _TokenType_name[551:563],
+	3100: _TokenType_name[563:576],
+	3101: _TokenType_name[576:594],
+	3102: _TokenType_name[594:611],
+	3103: _TokenType_name[611:632],
+	3104: _TokenType_name[632:652],
+	3105: _TokenType_name[652:669],
+	3106: _TokenType_name[669:691],
+	3107: _TokenType_name[691:707],
+	3108: _TokenType_name[707:726],
+	3109: _TokenType_name[726:745],
+	3110: _TokenType_name[745:765],
+	3111: _TokenType_name[765:786],
+	3112: _TokenType_name[786:803],
+	3113: _TokenType_name[803:821],
+	3114: _TokenType_name[821:839],
+	3115: _TokenType_name[839:858],
+	3116: _TokenType_name[858:877],
+	3117: _TokenType_name[877:894],
+	3200: _TokenType_name[894:907],
+	3201: _TokenType_name[907:923],
+	3202: _TokenType_name[923:941],
+	3203: _TokenType_name[941:957],
+	3204: _TokenType_name[957:977],
+	3205: _TokenType_name[977:1001],
+	3206: _TokenType_name[1001:1017],
+	4000: _TokenType_name[1017:1025],
+	4001: _TokenType_name[1025:1037],
+	5000: _TokenType_name[1037:1048],
+	6000: _TokenType_name[1048:1055],
+	6001: _TokenType_name[1055:1070],
+	6002: _TokenType_name[1070:1086],
+	6003: _TokenType_name[1086:1099],
+	6004: _TokenType_name[1099:1113],
+	6005: _TokenType_name[1113:1124],
+	6100: _TokenType_name[1124:1138],
+	6101: _TokenType_name[1138:1156],
+	7000: _TokenType_name[1156:1163],
+	7001: _TokenType_name[1163:1177],
+	7002: _TokenType_name[1177:1188],
+	7003: _TokenType_name[1188:1200],
+	7004: _TokenType_name[1200:1214],
+	7005: _TokenType_name[1214:1229],
+	7006: _TokenType_name[1229:1242],
+	7007: _TokenType_name[1242:1255],
+	7008: _TokenType_name[1255:1268],
+	7009: _TokenType_name[1268:1285],
+	7010: _TokenType_name[1285:1301],
+	7011: _TokenType_name[1301:1317],
+	8000: _TokenType_name[1317:1321],
+	8001: _TokenType_name[1321:1335],
+	8002: _TokenType_name[1335:1345],
+	8003: _TokenType_name[1345:1360],
+	8004: _TokenType_name[1360:1373],
 }
 
 func (i TokenType) String() string {
@@ -43,6 +43,8 @@ const (
 	Other
 	// No highlighting.
 	None
+	// Cursor position marker.
+	Cursor
 	// Used as an EOF marker / nil token
 	EOFType TokenType = 0
 )
@@ -113,6 +115,7 @@ const (
 	LiteralStringRegex
 	LiteralStringSingle
 	LiteralStringSymbol
+	LiteralStringLink
 )
 
 // Literals.
@@ -144,6 +147,7 @@ const (
 	CommentMultiline
 	CommentSingle
 	CommentSpecial
+	CommentLink
 )
 
 // Preprocessor "comments".
@@ -174,6 +178,7 @@ const (
 	TextWhitespace
 	TextSymbol
 	TextPunctuation
+	TextParagraph
 )
 
 // Aliases.
@@ -196,6 +201,7 @@ const (
 	StringRegex     = LiteralStringRegex
 	StringSingle    = LiteralStringSingle
 	StringSymbol    = LiteralStringSymbol
+	StringLink      = LiteralStringLink
 
 	Number            = LiteralNumber
 	NumberBin         = LiteralNumberBin
@@ -217,8 +223,10 @@ var (
 		LineTable:        "lntable",
 		LineTableTD:      "lntd",
 		CodeLine:         "cl",
+		Cursor:           "cur",
 		Text:             "",
 		Whitespace:       "w",
+		TextParagraph:    "pb",
 		Error:            "err",
 		Other:            "x",
 		// I have no idea what this is used for...
@@ -271,6 +279,7 @@ var (
 		StringRegex:     "sr",
 		StringSingle:    "s1",
 		StringSymbol:    "ss",
+		StringLink:      "su",
 
 		Number:            "m",
 		NumberBin:         "mb",
@@ -292,6 +301,7 @@ var (
 		CommentPreprocFile: "cpf",
 		CommentSingle:      "c1",
 		CommentSpecial:     "cs",
+		CommentLink:        "cu",
 
 		Generic:           "g",
 		GenericDeleted:    "gd",
@@ -0,0 +1,30 @@
+package chroma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []Token
+		expected int
+	}{
+		{"ASCII", []Token{{Keyword, "func"}, {Whitespace, " "}, {NameFunction, "main"}}, 9},
+		{"CJK", []Token{{String, `"你好"`}, {Comment, "// 世界"}}, 6 + 7},
+		{"Fullwidth", []Token{{Text, "ＡＢ"}}, 4},
+		{"Combining", []Token{{Name, "cafe\u0301"}, {Text, "n\u0303"}}, 5},
+		{"ZeroWidth", []Token{{Text, "a\u200bb\u200dc\ufeff"}}, 3},
+		{"Emoji", []Token{{Comment, "🎉!"}}, 3},
+		{"ANSI", []Token{{Text, "\x1b[1;31mred\x1b[0m"}, {Text, "\x1b[38;5;12m中\x1b[m"}}, 5},
+		{"ControlCharacters", []Token{{Text, "a\n"}, {Text, "\x00b"}}, 2},
+	}
+	for _, test := range tests {
+		// nolint: scopelint
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, DisplayWidth(test.tokens))
+		})
+	}
+}